@@ -4,8 +4,10 @@ go 1.19
 
 require (
 	github.com/gin-gonic/gin v1.9.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/joho/godotenv v1.5.1
 	github.com/shopspring/decimal v1.3.1
+	go.etcd.io/bbolt v1.3.7
 )
 
 require (
@@ -0,0 +1,264 @@
+// Package chart draws time series line charts into PNG images with the
+// standard library only, labels use a built in bitmap font.
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	Blue   = color.RGBA{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff}
+	Orange = color.RGBA{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff}
+	Green  = color.RGBA{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff}
+	Red    = color.RGBA{R: 0xd6, G: 0x27, B: 0x28, A: 0xff}
+
+	background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	foreground = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	grid       = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+)
+
+const (
+	textScale   = 2
+	lineHeight  = (glyphHeight + 3) * textScale
+	marginLeft  = 120
+	marginRight = 24
+	marginTop   = 16 + lineHeight
+	// marginBottom fits the time labels under the last panel
+	marginBottom = 16 + lineHeight
+	panelGap     = 24
+	gridLines    = 4
+	timeTicks    = 4
+	timeLayout   = "01-02 15:04"
+)
+
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+type Series struct {
+	Name   string
+	Color  color.RGBA
+	Points []Point
+}
+
+// Panel is one plot area with its own y axis, every panel of a chart shares the time axis
+type Panel struct {
+	Title  string
+	Series []Series
+	// ZeroLine draws the y = 0 line when it is in range, e.g. for a spread
+	ZeroLine bool
+}
+
+type Chart struct {
+	Title  string
+	Width  int
+	Height int
+	Panels []Panel
+}
+
+// PNG renders the chart and encodes it
+func (c *Chart) PNG() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, c.Render()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Render draws the panels stacked from top to bottom with equal heights
+func (c *Chart) Render() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	drawText(img, marginLeft, 16, c.Title, foreground, textScale)
+
+	from, to, ok := c.timeRange()
+	if !ok || len(c.Panels) == 0 {
+		drawText(img, marginLeft, c.Height/2, "no data", foreground, textScale)
+		return img
+	}
+
+	n := len(c.Panels)
+	height := (c.Height - marginTop - marginBottom - (n-1)*panelGap) / n
+	for i, p := range c.Panels {
+		top := marginTop + i*(height+panelGap)
+		area := image.Rect(marginLeft, top+lineHeight, c.Width-marginRight, top+height)
+		p.render(img, area, from, to)
+	}
+
+	// time labels under the last panel
+	left, right := marginLeft, c.Width-marginRight
+	y := c.Height - marginBottom + 8
+	for i := 0; i <= timeTicks; i++ {
+		x := left + (right-left)*i/timeTicks
+		label := from.Add(to.Sub(from) * time.Duration(i) / timeTicks).Format(timeLayout)
+		w := textWidth(label, textScale)
+		x -= w / 2
+		if x+w > c.Width {
+			x = c.Width - w
+		}
+		if x < 0 {
+			x = 0
+		}
+		drawText(img, x, y, label, foreground, textScale)
+	}
+	return img
+}
+
+func (c *Chart) timeRange() (from time.Time, to time.Time, ok bool) {
+	for _, p := range c.Panels {
+		for _, s := range p.Series {
+			for _, v := range s.Points {
+				if !ok || v.Time.Before(from) {
+					from = v.Time
+				}
+				if !ok || v.Time.After(to) {
+					to = v.Time
+				}
+				ok = true
+			}
+		}
+	}
+	if ok && !to.After(from) {
+		to = from.Add(time.Minute)
+	}
+	return from, to, ok
+}
+
+func (p *Panel) valueRange() (lo float64, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, s := range p.Series {
+		for _, v := range s.Points {
+			lo, hi = math.Min(lo, v.Value), math.Max(hi, v.Value)
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return 0, 1
+	}
+	if hi == lo {
+		return lo - 1, hi + 1
+	}
+	pad := (hi - lo) * 0.05
+	return lo - pad, hi + pad
+}
+
+func (p *Panel) render(img *image.RGBA, area image.Rectangle, from time.Time, to time.Time) {
+	// legend above the plot area
+	x := area.Min.X
+	drawText(img, x, area.Min.Y-lineHeight, p.Title, foreground, textScale)
+	x += textWidth(p.Title, textScale) + 16
+	for _, s := range p.Series {
+		fillRect(img, x, area.Min.Y-lineHeight+2*textScale, 12, 3*textScale, s.Color)
+		x += 18
+		drawText(img, x, area.Min.Y-lineHeight, s.Name, s.Color, textScale)
+		x += textWidth(s.Name, textScale) + 16
+	}
+
+	lo, hi := p.valueRange()
+	toY := func(v float64) int {
+		return area.Max.Y - int(math.Round((v-lo)/(hi-lo)*float64(area.Dy())))
+	}
+	toX := func(t time.Time) int {
+		return area.Min.X + int(math.Round(float64(t.Sub(from))/float64(to.Sub(from))*float64(area.Dx())))
+	}
+
+	// grid and value labels
+	prec := precision(hi - lo)
+	for i := 0; i <= gridLines; i++ {
+		v := lo + (hi-lo)*float64(i)/gridLines
+		y := toY(v)
+		hLine(img, area.Min.X, area.Max.X, y, grid)
+		label := strconv.FormatFloat(v, 'f', prec, 64)
+		drawText(img, area.Min.X-8-textWidth(label, textScale), y-glyphHeight*textScale/2, label, foreground, textScale)
+	}
+	if p.ZeroLine && lo < 0 && hi > 0 {
+		hLine(img, area.Min.X, area.Max.X, toY(0), foreground)
+	}
+
+	for _, s := range p.Series {
+		for i := 1; i < len(s.Points); i++ {
+			a, b := s.Points[i-1], s.Points[i]
+			line(img, toX(a.Time), toY(a.Value), toX(b.Time), toY(b.Value), s.Color)
+		}
+		if len(s.Points) == 1 {
+			v := s.Points[0]
+			fillRect(img, toX(v.Time)-2, toY(v.Value)-2, 5, 5, s.Color)
+		}
+	}
+
+	// frame last so lines never cover it
+	hLine(img, area.Min.X, area.Max.X, area.Min.Y, foreground)
+	hLine(img, area.Min.X, area.Max.X, area.Max.Y, foreground)
+	vLine(img, area.Min.X, area.Min.Y, area.Max.Y, foreground)
+	vLine(img, area.Max.X, area.Min.Y, area.Max.Y, foreground)
+}
+
+// precision is the number of decimals that tell grid lines spanning span apart
+func precision(span float64) int {
+	switch {
+	case span >= 100:
+		return 0
+	case span >= 1:
+		return 2
+	default:
+		return 4
+	}
+}
+
+func hLine(img *image.RGBA, x0, x1, y int, c color.Color) {
+	for x := x0; x <= x1; x++ {
+		img.Set(x, y, c)
+	}
+}
+
+func vLine(img *image.RGBA, x, y0, y1 int, c color.Color) {
+	for y := y0; y <= y1; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// line draws a two pixel wide line with Bresenham's algorithm
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		img.Set(x0, y0+1, c)
+		img.Set(x0+1, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
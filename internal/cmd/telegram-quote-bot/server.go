@@ -2,27 +2,100 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/aggregate"
 	comp "github.com/gummy789j/telegram-quote-bot/internal/repository/comparison"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/history"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/max"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/quote_book"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/quote_cache"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/quote_history"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/quote_validator"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/settings"
 	tb "github.com/gummy789j/telegram-quote-bot/internal/repository/telegram_bot"
 	"github.com/gummy789j/telegram-quote-bot/internal/task"
 	"github.com/gummy789j/telegram-quote-bot/internal/transport"
 	"github.com/gummy789j/telegram-quote-bot/internal/usecase"
+
+	// direct exchange adapters register themselves in the exchange package
+	_ "github.com/gummy789j/telegram-quote-bot/internal/repository/ace"
+	_ "github.com/gummy789j/telegram-quote-bot/internal/repository/binance_p2p"
+	_ "github.com/gummy789j/telegram-quote-bot/internal/repository/bitopro"
+	_ "github.com/gummy789j/telegram-quote-bot/internal/repository/rybit"
 )
 
 func RunServer(ctx context.Context, cfg *config.Config) *gin.Engine {
 
-	telegramBotRepo := tb.NewTelegramBotRepo(transport.NewHttpClient(), cfg.Telegram)
-	comparisonRepo := comp.NewComparisonClient(transport.NewHttpClient())
-	telegramUseCase := usecase.NewTelegramUseCase(cfg.Telegram, telegramBotRepo, comparisonRepo)
+	interceptors := transport.WithInterceptors(
+		transport.RedactInterceptor(),
+		transport.LoggingInterceptor(false),
+		transport.RetryInterceptor(transport.DefaultRetryPolicy()),
+	)
+	telegramCli := transport.NewHttpClient(interceptors, transport.WithRateLimit(tb.RateLimitRule(cfg.Telegram)))
+
+	// breaker notices skip the breakers, an open Telegram circuit would swallow its own notice
+	noticeRepo := tb.NewTelegramBotRepo(telegramCli, cfg.Telegram)
+	breakerSettings := transport.DefaultCircuitBreakerSettings()
+	breakerSettings.OnOpen = func(endpoint string, err error) {
+		msg := fmt.Sprintf("%s: %v", endpoint, err)
+		log.Println("circuit breaker opened: ", msg)
+		notifyAdmin(noticeRepo, cfg.Telegram.AdminChatID, "Circuit Breaker Opened", msg)
+	}
+	breakerSettings.OnClose = func(endpoint string, downtime time.Duration) {
+		msg := fmt.Sprintf("%s recovered after %s", endpoint, downtime.Truncate(time.Second))
+		log.Println("circuit breaker closed: ", msg)
+		notifyAdmin(noticeRepo, cfg.Telegram.AdminChatID, "Circuit Breaker Closed", msg)
+	}
+
+	telegramBotRepo := tb.NewTelegramBotRepo(transport.NewCircuitBreakerClient(telegramCli, breakerSettings), cfg.Telegram)
+	quoteCli := transport.NewCircuitBreakerClient(transport.NewHttpClient(interceptors), breakerSettings)
+
+	// the aggregator is listed first so it wins ties with the direct adapters,
+	// every source is validated on its own so a bad price cannot shadow a good one
+	quoteSources := []aggregate.Source{{Name: comp.SourceName, Repo: quote_validator.NewQuoteValidator(comp.NewComparisonClient(quoteCli))}}
+	adapters := exchange.NewAdapters(quoteCli, cfg.Exchange)
+	for _, v := range exchange.Exchanges() {
+		quoteSources = append(quoteSources, aggregate.Source{Name: string(v), Repo: quote_validator.NewQuoteValidator(adapters[v])})
+	}
+
+	// streamed quotes sit in front of the cache, the freshest price of a leg wins
+	pollRepo := quote_cache.NewQuoteCache(aggregate.NewAggregateClient(quoteSources...), cfg.Telegram.QuoteComparisonBot.QuoteCacheTTL)
+	quoteBook := quote_book.NewQuoteBook()
+	historyRepo, err := history.NewBoltHistory(cfg.History.Path, cfg.History.RawRetention, cfg.History.MinuteRetention)
+	if err != nil {
+		panic(err)
+	}
+	quoteRepo := quote_history.NewHistoryRecorder(aggregate.NewAggregateClient(
+		aggregate.Source{Name: max.StreamSourceName, Repo: quote_validator.NewQuoteValidator(quoteBook)},
+		aggregate.Source{Name: "poll", Repo: pollRepo},
+	), historyRepo)
+	depthRepo := exchange.NewDepthRouter(adapters)
+	settingsRepo, err := settings.NewBoltSettings(cfg.Settings.Path)
+	if err != nil {
+		panic(err)
+	}
+	telegramUseCase := usecase.NewTelegramUseCase(cfg.Telegram, telegramBotRepo, quoteRepo, depthRepo, historyRepo, settingsRepo)
+
+	pairs := []constant.Pair{}
+	for _, v := range cfg.Telegram.QuoteComparisonBot.Pairs {
+		pairs = append(pairs, v.Pair)
+	}
+	go max.NewMaxStream(cfg.Exchange.MaxStreamEndpoint, pairs).Run(ctx, quoteBook)
 
 	tasks := []task.Task{
 		task.NewNotifyTask(cfg.Telegram, telegramUseCase),
+		task.NewCycleNotifyTask(cfg.Telegram, telegramUseCase),
+		task.NewStreamNotifyTask(cfg.Telegram, telegramUseCase, quoteBook.Subscribe()),
 		task.NewReplyTask(cfg.Telegram, telegramUseCase),
+		task.NewHistoryCompactTask(cfg.History, historyRepo),
 	}
 
 	jobProcessor(ctx, tasks)
@@ -40,6 +113,23 @@ func RunServer(ctx context.Context, cfg *config.Config) *gin.Engine {
 	return g
 }
 
+// notifyAdminTimeout bounds an admin notification including its retries
+const notifyAdminTimeout = 30 * time.Second
+
+func notifyAdmin(telegramBotRepo dRepo.TelegramBotRepo, chatID int64, title string, msg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyAdminTimeout)
+	defer cancel()
+
+	err := telegramBotRepo.SendErrorNotify(ctx, dRepo.SendErrorNotifyRequest{
+		ChatID: chatID,
+		Title:  title,
+		ErrMsg: msg,
+	})
+	if err != nil {
+		log.Println("send admin notify failed: ", err.Error())
+	}
+}
+
 func jobProcessor(pctx context.Context, tasks []task.Task) {
 	for _, t := range tasks {
 
@@ -47,7 +137,10 @@ func jobProcessor(pctx context.Context, tasks []task.Task) {
 
 			runTime, tickTime := t.Freq()
 
-			ctx, cancel := context.WithTimeout(pctx, runTime)
+			ctx, cancel := context.WithCancel(pctx)
+			if runTime != 0 {
+				ctx, cancel = context.WithTimeout(pctx, runTime)
+			}
 			defer cancel()
 
 			ticker := time.NewTicker(tickTime)
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"time"
 
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/shopspring/decimal"
 )
@@ -10,6 +12,9 @@ import (
 type Config struct {
 	APIServer *APIServerCfg
 	Telegram  *TelegramCfg
+	Exchange  *ExchangeCfg
+	History   *HistoryCfg
+	Settings  *SettingsCfg
 }
 
 func NewConfig(isDev ...bool) *Config {
@@ -26,11 +31,36 @@ func NewConfig(isDev ...bool) *Config {
 	if len(telegramToken) == 0 {
 		panic("TELEGRAM_BOT_TOKEN is not set")
 	}
+
+	exchangeCfg := &ExchangeCfg{
+		MaxEndpoint:        getEnv("MAX_API_ENDPOINT", "https://max-api.maicoin.com"),
+		MaxStreamEndpoint:  getEnv("MAX_STREAM_ENDPOINT", "wss://max-stream.maicoin.com/ws"),
+		RybitEndpoint:      getEnv("RYBIT_API_ENDPOINT", "https://www.rybit.com/wallet-api"),
+		BitoProEndpoint:    getEnv("BITOPRO_API_ENDPOINT", "https://api.bitopro.com"),
+		BinanceP2PEndpoint: getEnv("BINANCE_P2P_API_ENDPOINT", "https://p2p.binance.com"),
+		ACEEndpoint:        getEnv("ACE_API_ENDPOINT", "https://ace.io"),
+		StaleQuoteAge:      3 * time.Minute,
+		StaleQuoteAgeByExchange: map[constant.Exchange]time.Duration{
+			constant.Rybit: 5 * time.Minute,
+		},
+	}
+
 	return &Config{
 		APIServer: &APIServerCfg{
 			Port: port,
 		},
+		Exchange: exchangeCfg,
+		History: &HistoryCfg{
+			Path:            getEnv("QUOTE_HISTORY_PATH", "quote_history.db"),
+			RawRetention:    7 * 24 * time.Hour,
+			MinuteRetention: 90 * 24 * time.Hour,
+			CompactInterval: time.Hour,
+		},
+		Settings: &SettingsCfg{
+			Path: getEnv("CHAT_SETTINGS_PATH", "chat_settings.db"),
+		},
 		Telegram: &TelegramCfg{
+			APIEndpoint: getEnv("TELEGRAM_API_ENDPOINT", "https://api.telegram.org"),
 			AdminChatID: 1881712391,
 			AuthorID:    1881712391,
 			Author:      "t.me/gummy789j",
@@ -46,6 +76,89 @@ func NewConfig(isDev ...bool) *Config {
 				MinArbitrage:     decimal.NewFromFloat(0.005),
 				ExcitedSpread:    decimal.NewFromFloat(0.3),
 				ExcitedArbitrage: decimal.NewFromFloat(0.01),
+				ArbitrageTopN:    3,
+
+				// quote, staleness is judged by the exchange config
+				QuoteCacheTTL: 10 * time.Second,
+				exchange:      exchangeCfg,
+
+				// streaming
+				StreamCheckInterval: 2 * time.Second,
+				StreamAlertCooldown: time.Minute,
+
+				// pairs, spreads are in TWD so each pair has its own
+				Pairs: []*PairCfg{
+					{
+						Pair:         constant.USDTTWD,
+						ExchangeBuy:  constant.Rybit,
+						ExchangeSell: constant.MAX,
+						Network:      constant.TRC20,
+						Notify:       true,
+					},
+					{
+						Pair:         constant.Pair{Base: constant.USDC, Quote: constant.TWD},
+						ExchangeBuy:  constant.BitoPro,
+						ExchangeSell: constant.MAX,
+						Network:      constant.ERC20,
+						Notify:       true,
+					},
+					{
+						Pair:          constant.Pair{Base: constant.BTC, Quote: constant.TWD},
+						ExchangeBuy:   constant.BitoPro,
+						ExchangeSell:  constant.MAX,
+						Network:       constant.Bitcoin,
+						MinSpread:     decimal.NewFromInt(10000),
+						ExcitedSpread: decimal.NewFromInt(30000),
+					},
+					{
+						Pair:          constant.Pair{Base: constant.ETH, Quote: constant.TWD},
+						ExchangeBuy:   constant.BitoPro,
+						ExchangeSell:  constant.MAX,
+						Network:       constant.ERC20,
+						MinSpread:     decimal.NewFromInt(500),
+						ExcitedSpread: decimal.NewFromInt(1500),
+					},
+					{
+						// a crypto quoted market, mostly a leg of multi hop cycles
+						Pair:          constant.Pair{Base: constant.USDC, Quote: constant.USDT},
+						ExchangeBuy:   constant.BitoPro,
+						ExchangeSell:  constant.MAX,
+						Network:       constant.ERC20,
+						MinSpread:     decimal.NewFromFloat(0.002),
+						ExcitedSpread: decimal.NewFromFloat(0.005),
+					},
+				},
+
+				// cycles, multi hop routes starting and ending in TWD
+				MaxCycleHops:       3,
+				CycleAlertCooldown: 10 * time.Minute,
+
+				// fees, a snapshot of the fee pages of each exchange
+				Fees: map[constant.Exchange]*FeeSchedule{
+					constant.MAX: {
+						TakerFee:         decimal.NewFromFloat(0.0015),
+						WithdrawalFee:    defaultWithdrawalFees(),
+						TWDWithdrawalFee: decimal.NewFromInt(15),
+					},
+					constant.Rybit: {
+						TakerFee:         decimal.NewFromFloat(0.001),
+						WithdrawalFee:    defaultWithdrawalFees(),
+						TWDWithdrawalFee: decimal.NewFromInt(15),
+					},
+					constant.BitoPro: {
+						TakerFee:         decimal.NewFromFloat(0.002),
+						WithdrawalFee:    defaultWithdrawalFees(),
+						TWDWithdrawalFee: decimal.NewFromInt(15),
+					},
+					constant.ACE: {
+						TakerFee:         decimal.NewFromFloat(0.001),
+						WithdrawalFee:    defaultWithdrawalFees(),
+						TWDWithdrawalFee: decimal.NewFromInt(15),
+					},
+					constant.BinanceP2P: {
+						WithdrawalFee: defaultWithdrawalFees(),
+					},
+				},
 			},
 		},
 	}
@@ -55,7 +168,43 @@ type APIServerCfg struct {
 	Port string
 }
 
+type ExchangeCfg struct {
+	MaxEndpoint        string
+	MaxStreamEndpoint  string
+	RybitEndpoint      string
+	BitoProEndpoint    string
+	BinanceP2PEndpoint string
+	ACEEndpoint        string
+	// StaleQuoteAge is how old a price of an exchange may be, adapters reject older ones and
+	// alerts skip them. StaleQuoteAgeByExchange overrides it per exchange
+	StaleQuoteAge           time.Duration
+	StaleQuoteAgeByExchange map[constant.Exchange]time.Duration
+}
+
+// StaleQuoteAgeOf returns how old a price of the exchange may be before it is ignored
+func (c *ExchangeCfg) StaleQuoteAgeOf(exchange constant.Exchange) time.Duration {
+	if age, ok := c.StaleQuoteAgeByExchange[exchange]; ok {
+		return age
+	}
+	return c.StaleQuoteAge
+}
+
+type HistoryCfg struct {
+	Path string
+	// raw quotes older than RawRetention are kept as 1 minute OHLC, those older
+	// than MinuteRetention as hourly OHLC
+	RawRetention    time.Duration
+	MinuteRetention time.Duration
+	CompactInterval time.Duration
+}
+
+// SettingsCfg is where the thresholds chats override with /set are kept
+type SettingsCfg struct {
+	Path string
+}
+
 type TelegramCfg struct {
+	APIEndpoint        string
 	AdminChatID        int64
 	AuthorID           int64
 	Author             string
@@ -72,6 +221,125 @@ type quoteComparisonBot struct {
 	MinArbitrage     decimal.Decimal
 	ExcitedSpread    decimal.Decimal
 	ExcitedArbitrage decimal.Decimal
+	QuoteCacheTTL    time.Duration
+	// ArbitrageTopN is how many of the best routes /arbitrage lists
+	ArbitrageTopN int
+	// StreamCheckInterval spaces out the arbitrage checks of a pair triggered by
+	// streamed price changes, StreamAlertCooldown the alerts they send
+	StreamCheckInterval time.Duration
+	StreamAlertCooldown time.Duration
+	// exchange holds the staleness thresholds shared with the adapters
+	exchange *ExchangeCfg
+	// MaxCycleHops is the most trades of a multi hop cycle, below 3 turns cycles off
+	// since two trades are a plain route
+	MaxCycleHops int
+	// CycleAlertCooldown is how long a cycle waits before alerting the same chat again
+	CycleAlertCooldown time.Duration
+	// Pairs are the watched pairs, the first one is the default of commands
+	Pairs []*PairCfg
+	Fees  map[constant.Exchange]*FeeSchedule
+}
+
+// PairCfg is the arbitrage route watched for a pair
+type PairCfg struct {
+	Pair constant.Pair
+	// ExchangeBuy and ExchangeSell are the route of /history and /stats,
+	// alerts and /arbitrage scan every route between the quoting exchanges
+	ExchangeBuy  constant.Exchange
+	ExchangeSell constant.Exchange
+	// Network is the chain the base asset moves on from the buy to the sell exchange
+	Network constant.Network
+	// Notify runs the arbitrage notify task for the pair
+	Notify bool
+	// MinSpread and ExcitedSpread are in the quote currency, zero uses the bot wide ones
+	MinSpread     decimal.Decimal
+	ExcitedSpread decimal.Decimal
+}
+
+// FeeSchedule is what an exchange charges, rates are of the traded amount and flat fees per transfer
+type FeeSchedule struct {
+	TakerFee decimal.Decimal
+	// WithdrawalFee is the flat fee of withdrawing an asset on a network, in that asset
+	WithdrawalFee    map[constant.Symbol]map[constant.Network]decimal.Decimal
+	TWDDepositFee    decimal.Decimal
+	TWDWithdrawalFee decimal.Decimal
+}
+
+func defaultWithdrawalFees() map[constant.Symbol]map[constant.Network]decimal.Decimal {
+	return map[constant.Symbol]map[constant.Network]decimal.Decimal{
+		constant.USDT: {constant.TRC20: decimal.NewFromInt(1), constant.ERC20: decimal.NewFromInt(10)},
+		constant.USDC: {constant.ERC20: decimal.NewFromInt(10)},
+		constant.BTC:  {constant.Bitcoin: decimal.NewFromFloat(0.0002)},
+		constant.ETH:  {constant.ERC20: decimal.NewFromFloat(0.003)},
+	}
+}
+
+// StaleQuoteAgeOf returns ExchangeCfg.StaleQuoteAgeOf, zero without an exchange config
+func (b *quoteComparisonBot) StaleQuoteAgeOf(exchange constant.Exchange) time.Duration {
+	if b.exchange == nil {
+		return 0
+	}
+	return b.exchange.StaleQuoteAgeOf(exchange)
+}
+
+// PairCfgOf returns the watched route of the pair
+func (b *quoteComparisonBot) PairCfgOf(pair constant.Pair) (*PairCfg, bool) {
+	for _, v := range b.Pairs {
+		if v.Pair == pair {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// NetworkOf returns the network the asset moves on between exchanges, taken from
+// the first pair config with the asset as base
+func (b *quoteComparisonBot) NetworkOf(asset constant.Symbol) constant.Network {
+	for _, v := range b.Pairs {
+		if v.Pair.Base == asset {
+			return v.Network
+		}
+	}
+	return ""
+}
+
+// DefaultPair is the pair of commands without a pair argument
+func (b *quoteComparisonBot) DefaultPair() constant.Pair {
+	if len(b.Pairs) == 0 {
+		return constant.USDTTWD
+	}
+	return b.Pairs[0].Pair
+}
+
+// MinSpreadOf returns the spread of the pair worth an alert
+func (b *quoteComparisonBot) MinSpreadOf(pair constant.Pair) decimal.Decimal {
+	if cfg, ok := b.PairCfgOf(pair); ok && !cfg.MinSpread.IsZero() {
+		return cfg.MinSpread
+	}
+	return b.MinSpread
+}
+
+// ExcitedSpreadOf returns the spread of the pair worth a celebration
+func (b *quoteComparisonBot) ExcitedSpreadOf(pair constant.Pair) decimal.Decimal {
+	if cfg, ok := b.PairCfgOf(pair); ok && !cfg.ExcitedSpread.IsZero() {
+		return cfg.ExcitedSpread
+	}
+	return b.ExcitedSpread
+}
+
+// FeesOf returns the fee schedule of the exchange, an exchange without one is free
+func (b *quoteComparisonBot) FeesOf(exchange constant.Exchange) FeeSchedule {
+	if fees, ok := b.Fees[exchange]; ok && fees != nil {
+		return *fees
+	}
+	return FeeSchedule{}
+}
+
+func getEnv(key string, fallback string) string {
+	if v := os.Getenv(key); len(v) > 0 {
+		return v
+	}
+	return fallback
 }
 
 var isDevelopment bool = false
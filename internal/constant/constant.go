@@ -1,17 +1,98 @@
 package constant
 
+import "strings"
+
 type CommandType string
 
 var (
-	Alive     CommandType = "alive"
-	Help      CommandType = "help"
-	Depth     CommandType = "depth"
-	Arbitrage CommandType = "arbitrage"
+	Alive       CommandType = "alive"
+	Help        CommandType = "help"
+	Depth       CommandType = "depth"
+	Arbitrage   CommandType = "arbitrage"
+	History     CommandType = "history"
+	Stats       CommandType = "stats"
+	Set         CommandType = "set"
+	Settings    CommandType = "settings"
+	Subscribe   CommandType = "subscribe"
+	Unsubscribe CommandType = "unsubscribe"
 )
 
 type Exchange string
 
 var (
-	MAX   Exchange = "MAX"
-	Rybit Exchange = "Rybit"
+	MAX        Exchange = "MAX"
+	Rybit      Exchange = "Rybit"
+	BitoPro    Exchange = "BitoPro"
+	BinanceP2P Exchange = "BinanceP2P"
+	ACE        Exchange = "ACE"
+)
+
+// Network is a chain USDT is transferred on between exchanges
+type Network string
+
+var (
+	TRC20   Network = "TRC20"
+	ERC20   Network = "ERC20"
+	Bitcoin Network = "BTC"
 )
+
+// exchangeNames maps normalized upstream names to known exchanges
+var exchangeNames = map[string]Exchange{
+	"max":        MAX,
+	"maicoin":    MAX,
+	"rybit":      Rybit,
+	"bitopro":    BitoPro,
+	"binancep2p": BinanceP2P,
+	"binance":    BinanceP2P,
+	"ace":        ACE,
+}
+
+// LookupExchange maps an upstream exchange name, e.g. "Max" or "binance_p2p", to a known exchange
+func LookupExchange(name string) (Exchange, bool) {
+	normalized := strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+	exchange, ok := exchangeNames[normalized]
+	return exchange, ok
+}
+
+type Symbol string
+
+var (
+	USDT Symbol = "USDT"
+	USDC Symbol = "USDC"
+	BTC  Symbol = "BTC"
+	ETH  Symbol = "ETH"
+	TWD  Symbol = "TWD"
+)
+
+var symbols = map[Symbol]bool{USDT: true, USDC: true, BTC: true, ETH: true, TWD: true}
+
+// Pair is a market, Base is traded and priced in Quote
+type Pair struct {
+	Base  Symbol
+	Quote Symbol
+}
+
+var USDTTWD = Pair{Base: USDT, Quote: TWD}
+
+func (p Pair) String() string {
+	return string(p.Base) + "/" + string(p.Quote)
+}
+
+// LookupPair parses "USDC", "usdc/twd" or "BTC-TWD" into a pair of known symbols,
+// the quote defaults to TWD
+func LookupPair(name string) (Pair, bool) {
+	parts := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool { return r == '/' || r == '-' || r == '_' })
+	switch len(parts) {
+	case 1:
+		parts = append(parts, string(TWD))
+	case 2:
+	default:
+		return Pair{}, false
+	}
+
+	pair := Pair{Base: Symbol(parts[0]), Quote: Symbol(parts[1])}
+	if !symbols[pair.Base] || !symbols[pair.Quote] || pair.Base == pair.Quote {
+		return Pair{}, false
+	}
+	return pair, true
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	"github.com/shopspring/decimal"
+)
+
+var ErrDepthUnsupported = errors.New("order book depth is not supported")
+
+type DepthRepo interface {
+	GetDepth(ctx context.Context, req GetDepthRequest) (*GetDepthResponse, error)
+}
+
+// GetDepthRequest asks for the book of one pair, an empty Base or Quote means USDT/TWD
+type GetDepthRequest struct {
+	Exchange constant.Exchange
+	Base     constant.Symbol
+	Quote    constant.Symbol
+	Limit    int
+}
+
+func (r GetDepthRequest) Pair() constant.Pair {
+	return GetQuotationsRequest{Base: r.Base, Quote: r.Quote}.Pair()
+}
+
+type GetDepthResponse struct {
+	Exchange constant.Exchange
+	Pair     constant.Pair
+	// Asks are sorted from the best (lowest) price, Bids from the best (highest) price
+	Asks       []DepthLevel
+	Bids       []DepthLevel
+	UpdateTime time.Time
+}
+
+// Sort puts the best prices first and cuts both sides to limit levels, limit <= 0 keeps all
+func (r *GetDepthResponse) Sort(limit int) {
+	sort.Slice(r.Asks, func(i, j int) bool { return r.Asks[i].Price.LessThan(r.Asks[j].Price) })
+	sort.Slice(r.Bids, func(i, j int) bool { return r.Bids[i].Price.GreaterThan(r.Bids[j].Price) })
+
+	if limit > 0 && len(r.Asks) > limit {
+		r.Asks = r.Asks[:limit]
+	}
+	if limit > 0 && len(r.Bids) > limit {
+		r.Bids = r.Bids[:limit]
+	}
+}
+
+type DepthLevel struct {
+	Price  decimal.Decimal
+	Volume decimal.Decimal
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	"github.com/shopspring/decimal"
+)
+
+// Resolution is the time span a history point covers
+type Resolution string
+
+var (
+	ResolutionRaw    Resolution = "raw"
+	ResolutionMinute Resolution = "1m"
+	ResolutionHour   Resolution = "1h"
+)
+
+// Duration is the span of a bucket, zero for raw points
+func (r Resolution) Duration() time.Duration {
+	switch r {
+	case ResolutionMinute:
+		return time.Minute
+	case ResolutionHour:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+type HistoryRepo interface {
+	// RecordQuotations stores every quote of a snapshot at its FetchedAt
+	RecordQuotations(ctx context.Context, snapshot *GetQuotationsResponse) error
+	GetHistory(ctx context.Context, req GetHistoryRequest) (*GetHistoryResponse, error)
+	// Compact downsamples and drops points that are past their retention at now
+	Compact(ctx context.Context, now time.Time) error
+}
+
+// GetHistoryRequest asks for the points of one pair in [From, To], an empty
+// Exchange means every exchange and an empty pair means USDT/TWD
+type GetHistoryRequest struct {
+	Exchange   constant.Exchange
+	Base       constant.Symbol
+	Quote      constant.Symbol
+	From       time.Time
+	To         time.Time
+	Resolution Resolution
+}
+
+func (r GetHistoryRequest) Pair() constant.Pair {
+	return GetQuotationsRequest{Base: r.Base, Quote: r.Quote}.Pair()
+}
+
+type GetHistoryResponse struct {
+	// Points are sorted by exchange, then by time
+	Points []HistoryPoint
+}
+
+// Series returns the points of one exchange
+func (r *GetHistoryResponse) Series(exchange constant.Exchange) []HistoryPoint {
+	points := []HistoryPoint{}
+	for _, v := range r.Points {
+		if v.Exchange == exchange {
+			points = append(points, v)
+		}
+	}
+	return points
+}
+
+// HistoryPoint is one recorded quote, or the OHLC of the quotes in a bucket
+// starting at Time when the resolution is coarser than raw
+type HistoryPoint struct {
+	Exchange   constant.Exchange
+	Pair       constant.Pair
+	Time       time.Time
+	Resolution Resolution
+	// Source and UpdateTime are of the last quote in the point
+	Source     string
+	UpdateTime time.Time
+	Buy        OHLC
+	Sell       OHLC
+	Count      int
+}
+
+type OHLC struct {
+	Open  decimal.Decimal
+	High  decimal.Decimal
+	Low   decimal.Decimal
+	Close decimal.Decimal
+}
+
+func NewOHLC(price decimal.Decimal) OHLC {
+	return OHLC{Open: price, High: price, Low: price, Close: price}
+}
+
+// Merge adds the later OHLC next to o
+func (o OHLC) Merge(next OHLC) OHLC {
+	return OHLC{
+		Open:  o.Open,
+		High:  decimal.Max(o.High, next.High),
+		Low:   decimal.Min(o.Low, next.Low),
+		Close: next.Close,
+	}
+}
+
+// Merge adds the later point next to p, keeping the bucket of p
+func (p HistoryPoint) Merge(next HistoryPoint) HistoryPoint {
+	p.Buy = p.Buy.Merge(next.Buy)
+	p.Sell = p.Sell.Merge(next.Sell)
+	p.Source = next.Source
+	p.UpdateTime = next.UpdateTime
+	p.Count += next.Count
+	return p
+}
@@ -2,25 +2,148 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 	"github.com/shopspring/decimal"
 )
 
+var (
+	ErrStaleQuote       = errors.New("quote is stale")
+	ErrMalformedQuote   = errors.New("quote is malformed")
+	ErrQuoteUnavailable = errors.New("quote source returned an error")
+	ErrQuoteMissing     = errors.New("quote is missing")
+	ErrInvalidPrice     = errors.New("quote price is not positive")
+	ErrInvertedQuote    = errors.New("quote bid is above ask")
+	ErrPairUnsupported  = errors.New("pair is not supported")
+	// ErrQuoteNotStreamed is returned by a live source holding no quote of a pair, either
+	// because the pair is not streamed or because its first snapshot is still to come
+	ErrQuoteNotStreamed = errors.New("quote is not streamed")
+)
+
+// QuoteKey identifies the quote of one pair on one exchange
+type QuoteKey struct {
+	Exchange constant.Exchange
+	Pair     constant.Pair
+}
+
+func (k QuoteKey) String() string {
+	return fmt.Sprintf("%s %s", k.Exchange, k.Pair)
+}
+
+// QuoteError tells which exchange and pair a quote error belongs to
+type QuoteError struct {
+	Exchange constant.Exchange
+	Pair     constant.Pair
+	Err      error
+	Reason   string
+}
+
+func (e *QuoteError) Error() string {
+	where := string(e.Exchange)
+	if e.Pair != (constant.Pair{}) {
+		where = QuoteKey{Exchange: e.Exchange, Pair: e.Pair}.String()
+	}
+	if len(e.Reason) == 0 {
+		return fmt.Sprintf("%s: %s", where, e.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s: %s", where, e.Err.Error(), e.Reason)
+}
+
+func (e *QuoteError) Unwrap() error {
+	return e.Err
+}
+
 type QuoteRepo interface {
 	GetQuotations(ctx context.Context, req GetQuotationsRequest) (*GetQuotationsResponse, error)
 }
 
+// QuoteUpdater takes quotes pushed by a streaming source
+type QuoteUpdater interface {
+	UpdateQuote(key QuoteKey, info QuotationInfo)
+}
+
+// GetQuotationsRequest asks for the quotes of one pair, an empty request means USDT/TWD
 type GetQuotationsRequest struct {
+	Base  constant.Symbol
+	Quote constant.Symbol
+}
+
+func NewGetQuotationsRequest(pair constant.Pair) GetQuotationsRequest {
+	return GetQuotationsRequest{Base: pair.Base, Quote: pair.Quote}
+}
+
+func (r GetQuotationsRequest) Pair() constant.Pair {
+	if len(r.Base) == 0 || len(r.Quote) == 0 {
+		return constant.USDTTWD
+	}
+	return constant.Pair{Base: r.Base, Quote: r.Quote}
 }
 
 type GetQuotationsResponse struct {
-	Infos map[constant.Exchange]QuotationInfo
+	Infos map[QuoteKey]QuotationInfo
+	// FetchedAt is when the snapshot was taken from upstream, cached responses keep it
+	FetchedAt time.Time
+	// Rejected holds the quotes that failed validation
+	Rejected map[QuoteKey]error
+}
+
+// Quotation returns the quote of the pair on the exchange, or a *QuoteError saying why there is none
+func (r *GetQuotationsResponse) Quotation(exchange constant.Exchange, pair constant.Pair) (QuotationInfo, error) {
+	key := QuoteKey{Exchange: exchange, Pair: pair}
+	if info, ok := r.Infos[key]; ok {
+		return info, nil
+	}
+	if err, ok := r.Rejected[key]; ok {
+		return QuotationInfo{}, err
+	}
+	return QuotationInfo{}, &QuoteError{Exchange: exchange, Pair: pair, Err: ErrQuoteMissing}
+}
+
+func (r *GetQuotationsResponse) Age() time.Duration {
+	return time.Since(r.FetchedAt)
 }
 
 type QuotationInfo struct {
 	BuyPrice   decimal.Decimal
 	SellPrice  decimal.Decimal
 	UpdateTime time.Time
+	// Source is the name of the quote source that produced the price
+	Source string
+}
+
+// Age is how long ago the exchange updated the price
+func (q QuotationInfo) Age() time.Duration {
+	return time.Since(q.UpdateTime)
+}
+
+// ValidateQuotation rejects zero, negative and inverted prices, BuyPrice is the
+// ask we buy at and SellPrice is the bid we sell at
+func ValidateQuotation(key QuoteKey, info QuotationInfo) error {
+	switch {
+	case !info.BuyPrice.IsPositive():
+		return &QuoteError{Exchange: key.Exchange, Pair: key.Pair, Err: ErrInvalidPrice, Reason: "buy price " + info.BuyPrice.String()}
+	case !info.SellPrice.IsPositive():
+		return &QuoteError{Exchange: key.Exchange, Pair: key.Pair, Err: ErrInvalidPrice, Reason: "sell price " + info.SellPrice.String()}
+	case info.SellPrice.GreaterThan(info.BuyPrice):
+		return &QuoteError{Exchange: key.Exchange, Pair: key.Pair, Err: ErrInvertedQuote, Reason: fmt.Sprintf("bid %s, ask %s", info.SellPrice, info.BuyPrice)}
+	}
+	return nil
+}
+
+// NewQuoteResponse is the response of a source quoting one pair. A quote older than maxAge
+// is put in Rejected with ErrStaleQuote, so that callers can tell a frozen price from a
+// missing one, a zero maxAge accepts any age.
+func NewQuoteResponse(key QuoteKey, info QuotationInfo, maxAge time.Duration) *GetQuotationsResponse {
+	resp := &GetQuotationsResponse{Infos: make(map[QuoteKey]QuotationInfo, 1), FetchedAt: time.Now()}
+	if age := info.Age(); maxAge > 0 && age > maxAge {
+		resp.Rejected = map[QuoteKey]error{
+			key: &QuoteError{Exchange: key.Exchange, Pair: key.Pair, Err: ErrStaleQuote, Reason: fmt.Sprintf("updated %s ago", age.Truncate(time.Second))},
+		}
+		return resp
+	}
+	resp.Infos[key] = info
+	return resp
 }
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SettingKey names a bot wide threshold a chat can override with /set
+type SettingKey string
+
+const (
+	SettingMinSpread        SettingKey = "min_spread"
+	SettingMinArbitrage     SettingKey = "min_arbitrage"
+	SettingExcitedSpread    SettingKey = "excited_spread"
+	SettingExcitedArbitrage SettingKey = "excited_arbitrage"
+	SettingDefaultInvest    SettingKey = "default_invest"
+)
+
+// SettingKeys are the keys in the order /settings lists them
+var SettingKeys = []SettingKey{
+	SettingMinSpread,
+	SettingMinArbitrage,
+	SettingExcitedSpread,
+	SettingExcitedArbitrage,
+	SettingDefaultInvest,
+}
+
+// LookupSettingKey accepts a key in any case, with dashes or underscores
+func LookupSettingKey(name string) (SettingKey, bool) {
+	key := SettingKey(strings.ReplaceAll(strings.ToLower(name), "-", "_"))
+	for _, v := range SettingKeys {
+		if v == key {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// IsRatio tells the keys holding a fraction of the invest, e.g. 0.005 for 0.5%
+func (k SettingKey) IsRatio() bool {
+	return k == SettingMinArbitrage || k == SettingExcitedArbitrage
+}
+
+type SettingsRepo interface {
+	// GetChatSettings returns settings without values for a chat that never set any
+	GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error)
+	SaveChatSettings(ctx context.Context, settings *ChatSettings) error
+	// ListChatSettings returns the settings of every chat that keeps any
+	ListChatSettings(ctx context.Context) ([]*ChatSettings, error)
+}
+
+type ChatSettings struct {
+	ChatID int64
+	// Values override the bot wide thresholds in the chat, a missing key keeps the bot wide one
+	Values map[SettingKey]decimal.Decimal
+	// Subscribed chats get the arbitrage alerts with their own thresholds
+	Subscribed bool
+	UpdatedBy  int64
+	UpdatedAt  time.Time
+}
+
+// IsEmpty tells settings holding nothing but the bot wide defaults
+func (s *ChatSettings) IsEmpty() bool {
+	return len(s.Values) == 0 && !s.Subscribed
+}
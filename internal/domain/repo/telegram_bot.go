@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 	"github.com/shopspring/decimal"
@@ -9,8 +10,14 @@ import (
 
 type TelegramBotRepo interface {
 	SendMessage(ctx context.Context, req SendMessageRequest) error
+	SendPhoto(ctx context.Context, req SendPhotoRequest) error
 	SendArbitrageNotify(ctx context.Context, req SendArbitrageNotifyRequest) error
+	SendArbitrageRankNotify(ctx context.Context, req SendArbitrageRankNotifyRequest) error
 	SendErrorNotify(ctx context.Context, req SendErrorNotifyRequest) error
+	SendDepthNotify(ctx context.Context, req SendDepthNotifyRequest) error
+	SendStatsNotify(ctx context.Context, req SendStatsNotifyRequest) error
+	SendSettingsNotify(ctx context.Context, req SendSettingsNotifyRequest) error
+	GetChatMember(ctx context.Context, req GetChatMemberRequest) (*GetChatMemberResponse, error)
 	GetUpdates(ctx context.Context, req GetUpdatesRequest) (*GetUpdatesResponse, error)
 	GetBotCommandUpdates(ctx context.Context, req GetBotCommandUpdatesRequest) (*GetBotCommandUpdatesResponse, error)
 }
@@ -21,17 +28,87 @@ type SendMessageRequest struct {
 	ParseMode string `json:"parse_mode"`
 }
 
+// SendPhotoRequest uploads Photo, e.g. a png, with an optional caption
+type SendPhotoRequest struct {
+	ChatID    int64
+	Photo     []byte
+	Filename  string
+	Caption   string
+	ParseMode string
+}
+
 type SendArbitrageNotifyRequest struct {
-	ChatID                              int64
-	InvestAmount                        decimal.Decimal
-	ExchangeBuy                         constant.Exchange
-	ExchangeSell                        constant.Exchange
-	BuyPrice                            decimal.Decimal
-	SellPrice                           decimal.Decimal
-	Spread                              decimal.Decimal
-	Arbitrage                           decimal.Decimal
-	Profit                              decimal.Decimal
+	ChatID       int64
+	Pair         constant.Pair
+	InvestAmount decimal.Decimal
+	ExchangeBuy  constant.Exchange
+	ExchangeSell constant.Exchange
+	BuyPrice     decimal.Decimal
+	SellPrice    decimal.Decimal
+	Spread       decimal.Decimal
+	Arbitrage    decimal.Decimal
+	Profit       decimal.Decimal
+	// prices and arbitrage of InvestAmount walked through both order books
+	VWAPBuyPrice, VWAPSellPrice decimal.Decimal
+	EffectiveArbitrage          decimal.Decimal
+	SlippageBps                 decimal.Decimal
+	// net of the fees of each leg, the usdt withdrawal belongs to the buy leg
+	NetArbitrage, NetProfit, NetSpread decimal.Decimal
+	BuyFees, SellFees                  LegFees
+	// MaxInvestAmount is the largest size still clearing MinArbitrage net, nil when unlimited
+	MaxInvestAmount                     *decimal.Decimal
+	QuoteAge                            time.Duration
+	BuyQuoteAge, SellQuoteAge           time.Duration
 	IsExcitedArbitrage, IsExcitedSpread bool
+	// Hops breaks down a multi hop cycle, which starts with InvestAmount of Pair.Quote
+	// on ExchangeBuy and ends in it on ExchangeSell. Empty for a plain route.
+	Hops []ArbitrageHop
+}
+
+// ArbitrageHop is one trade of a cycle, AmountIn of AssetIn becomes AmountOut of AssetOut
+type ArbitrageHop struct {
+	Exchange constant.Exchange
+	Pair     constant.Pair
+	// Buy buys Pair.Base with Pair.Quote, otherwise Pair.Base is sold
+	Buy       bool
+	Price     decimal.Decimal
+	AssetIn   constant.Symbol
+	AmountIn  decimal.Decimal
+	AssetOut  constant.Symbol
+	AmountOut decimal.Decimal
+	// TradingFee is in AssetOut, WithdrawalFee is what moving AssetIn here cost, in AssetIn
+	TradingFee    decimal.Decimal
+	WithdrawalFee decimal.Decimal
+}
+
+// SendArbitrageRankNotifyRequest lists routes of a pair from the best net arbitrage down
+type SendArbitrageRankNotifyRequest struct {
+	ChatID int64
+	Pair   constant.Pair
+	Routes []ArbitrageRoute
+}
+
+// ArbitrageRoute is a route walked through the order books for InvestAmount, net of fees
+type ArbitrageRoute struct {
+	ExchangeBuy     constant.Exchange
+	ExchangeSell    constant.Exchange
+	BuyPrice        decimal.Decimal
+	SellPrice       decimal.Decimal
+	InvestAmount    decimal.Decimal
+	NetArbitrage    decimal.Decimal
+	NetProfit       decimal.Decimal
+	MaxInvestAmount *decimal.Decimal
+}
+
+// LegFees are the costs of one arbitrage leg in TWD
+type LegFees struct {
+	Trading    decimal.Decimal
+	Withdrawal decimal.Decimal
+	Bank       decimal.Decimal
+}
+
+func (f LegFees) Total() decimal.Decimal {
+	return f.Trading.Add(f.Withdrawal).Add(f.Bank)
 }
 
 type SendErrorNotifyRequest struct {
@@ -40,6 +117,76 @@ type SendErrorNotifyRequest struct {
 	ErrMsg string
 }
 
+type SendDepthNotifyRequest struct {
+	ChatID int64
+	Depth  *GetDepthResponse
+}
+
+type SendStatsNotifyRequest struct {
+	ChatID       int64
+	Window       time.Duration
+	MinArbitrage decimal.Decimal
+	// TopN is how many routes of each pair are listed, the ones longest above MinArbitrage
+	TopN   int
+	Routes []RouteStats
+}
+
+// RouteStats summarizes the recorded quotes of buying on ExchangeBuy and selling on ExchangeSell
+type RouteStats struct {
+	Pair         constant.Pair
+	ExchangeBuy  constant.Exchange
+	ExchangeSell constant.Exchange
+	Samples      int
+	Spread       SummaryStats
+	Arbitrage    SummaryStats
+	// Opportunities counts the periods at or above MinArbitrage, OpportunityTime is their total length
+	Opportunities   int
+	OpportunityTime time.Duration
+}
+
+type SendSettingsNotifyRequest struct {
+	ChatID   int64
+	Settings []ChatSetting
+	// Subscribed tells whether the arbitrage alerts come to the chat
+	Subscribed bool
+}
+
+// ChatSetting is the value a chat alerts with, IsDefault when the chat did not set it
+type ChatSetting struct {
+	Key       SettingKey
+	Value     decimal.Decimal
+	IsDefault bool
+}
+
+type GetChatMemberRequest struct {
+	ChatID int64
+	UserID int64
+}
+
+type GetChatMemberResponse struct {
+	Status ChatMemberStatus
+}
+
+type ChatMemberStatus string
+
+var (
+	ChatMemberCreator       ChatMemberStatus = "creator"
+	ChatMemberAdministrator ChatMemberStatus = "administrator"
+	ChatMemberMember        ChatMemberStatus = "member"
+)
+
+func (r *GetChatMemberResponse) IsAdmin() bool {
+	return r.Status == ChatMemberCreator || r.Status == ChatMemberAdministrator
+}
+
+type SummaryStats struct {
+	Min    decimal.Decimal
+	Max    decimal.Decimal
+	Mean   decimal.Decimal
+	Median decimal.Decimal
+	P95    decimal.Decimal
+}
+
 type GetUpdatesRequest struct {
 	Offset int64 `json:"offset"`
 }
@@ -105,5 +252,6 @@ type BotCommandInfo struct {
 	FromChatID int64
 	FromID     int64
 	Command    constant.CommandType
+	Args       []string
 	Date       int64
 }
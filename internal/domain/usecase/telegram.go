@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 )
@@ -9,6 +10,7 @@ import (
 type TelegramUseCase interface {
 	ReplyCommand(ctx context.Context, req ReplyCommandRequest) error
 	NotifyArbitrage(ctx context.Context, req NotifyArbitrageRequest) error
+	NotifyCycleArbitrage(ctx context.Context, req NotifyCycleArbitrageRequest) error
 }
 
 type ReplyCommandRequest struct {
@@ -28,8 +30,20 @@ func (r *ReplyCommandRequest) InFromChatIDs(chatID int64) bool {
 	return false
 }
 
+// NotifyArbitrageRequest scans every route between the exchanges quoting Pair and alerts
+// ToChatID, and every chat that ran /subscribe, about each one above the thresholds of
+// that chat
 type NotifyArbitrageRequest struct {
-	ExchangeBuy  constant.Exchange
-	ExchangeSell constant.Exchange
-	ToChatID     int64
+	Pair     constant.Pair
+	ToChatID int64
+	// Cooldown skips the alert when the same route alerted the chat within it, zero always alerts
+	Cooldown time.Duration
+}
+
+// NotifyCycleArbitrageRequest alerts ToChatID, and every chat that ran /subscribe, about the
+// multi hop cycles through the watched pairs
+type NotifyCycleArbitrageRequest struct {
+	ToChatID int64
+	// Cooldown skips the alert when the same cycle alerted the chat within it, zero always alerts
+	Cooldown time.Duration
 }
@@ -0,0 +1,111 @@
+package ace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+type aceClient struct {
+	cli      transport.HttpClient
+	endpoint string
+	maxAge   time.Duration
+}
+
+var (
+	_ domain.QuoteRepo = (*aceClient)(nil)
+	_ domain.DepthRepo = (*aceClient)(nil)
+)
+
+func NewACEClient(cli transport.HttpClient, endpoint string, maxAge time.Duration) domain.QuoteRepo {
+	return &aceClient{
+		cli:      cli,
+		endpoint: endpoint,
+		maxAge:   maxAge,
+	}
+}
+
+func init() {
+	exchange.Register(constant.ACE, func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo {
+		return NewACEClient(cli, cfg.ACEEndpoint, cfg.StaleQuoteAgeOf(constant.ACE))
+	})
+}
+
+var (
+	pathOrderBook = "/polarisex/oapi/v2/list/orderBooks/%s/%s"
+)
+
+func (c *aceClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	pair := req.Pair()
+	depth, err := c.GetDepth(ctx, domain.GetDepthRequest{Exchange: constant.ACE, Base: pair.Base, Quote: pair.Quote, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	info := domain.QuotationInfo{
+		BuyPrice:   depth.Asks[0].Price,
+		SellPrice:  depth.Bids[0].Price,
+		UpdateTime: depth.UpdateTime,
+	}
+
+	return domain.NewQuoteResponse(domain.QuoteKey{Exchange: constant.ACE, Pair: pair}, info, c.maxAge), nil
+}
+
+func (c *aceClient) GetDepth(ctx context.Context, req domain.GetDepthRequest) (*domain.GetDepthResponse, error) {
+
+	pair := req.Pair()
+	url := fmt.Sprintf("%s%s", c.endpoint, fmt.Sprintf(pathOrderBook, pair.Base, pair.Quote))
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	respBody := &orderBookRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return nil, fmt.Errorf("%w: ace: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	if respBody.Status != http.StatusOK {
+		msg := ""
+		if respBody.Message != nil {
+			msg = *respBody.Message
+		}
+		return nil, fmt.Errorf("%w: ace %d: %s", domain.ErrQuoteUnavailable, respBody.Status, msg)
+	}
+
+	book := respBody.Attachment
+	if book == nil || len(book.Asks) == 0 || len(book.Bids) == 0 {
+		return nil, fmt.Errorf("%w: ace: empty order book", domain.ErrMalformedQuote)
+	}
+
+	resp := &domain.GetDepthResponse{
+		Exchange:   constant.ACE,
+		Pair:       pair,
+		UpdateTime: time.Now(),
+	}
+	if book.Timestamp > 0 {
+		resp.UpdateTime = time.UnixMilli(book.Timestamp)
+	}
+	for _, v := range book.Asks {
+		resp.Asks = append(resp.Asks, domain.DepthLevel{Price: v.Price, Volume: v.Amount})
+	}
+	for _, v := range book.Bids {
+		resp.Bids = append(resp.Bids, domain.DepthLevel{Price: v.Price, Volume: v.Amount})
+	}
+	// the api does not document the level order, Sort puts the top of book first
+	resp.Sort(req.Limit)
+
+	return resp, nil
+}
@@ -0,0 +1,21 @@
+package ace
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type orderBookLevel struct {
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// orderBookRespBody is GET /polarisex/oapi/v2/list/orderBooks/{base}/{quote}
+type orderBookRespBody struct {
+	Status     int     `json:"status"`
+	Message    *string `json:"message"`
+	Attachment *struct {
+		Asks      []orderBookLevel `json:"asks"`
+		Bids      []orderBookLevel `json:"bids"`
+		Timestamp int64            `json:"timestamp"`
+	} `json:"attachment"`
+}
@@ -0,0 +1,101 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+var ErrNoQuoteSource = errors.New("no quote source available")
+
+type Source struct {
+	Name string
+	Repo domain.QuoteRepo
+}
+
+type aggregateClient struct {
+	sources []Source
+}
+
+var _ domain.QuoteRepo = (*aggregateClient)(nil)
+
+// NewAggregateClient queries every source concurrently and merges the quotes
+// per exchange and pair, the freshest UpdateTime wins and ties go to the source
+// listed first. A failing source is skipped as long as another one answers.
+func NewAggregateClient(sources ...Source) domain.QuoteRepo {
+	return &aggregateClient{sources: sources}
+}
+
+type sourceResult struct {
+	resp *domain.GetQuotationsResponse
+	err  error
+}
+
+func (c *aggregateClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	results := make([]sourceResult, len(c.sources))
+
+	wg := sync.WaitGroup{}
+	for i, source := range c.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			resp, err := source.Repo.GetQuotations(ctx, req)
+			results[i] = sourceResult{resp: resp, err: err}
+		}(i, source)
+	}
+	wg.Wait()
+
+	infos := make(map[domain.QuoteKey]domain.QuotationInfo)
+	rejected := make(map[domain.QuoteKey]error)
+	var fetchedAt time.Time
+	errMsgs := []string{}
+
+	for i, result := range results {
+		name := c.sources[i].Name
+		if result.err != nil {
+			// a source without the pair is expected, not worth a log line
+			if !errors.Is(result.err, domain.ErrPairUnsupported) && !errors.Is(result.err, domain.ErrQuoteNotStreamed) {
+				log.Println("get quotations from", name, "failed: ", result.err.Error())
+			}
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: %s", name, result.err.Error()))
+			continue
+		}
+
+		// the snapshot is only as fresh as its oldest source
+		if fetchedAt.IsZero() || result.resp.FetchedAt.Before(fetchedAt) {
+			fetchedAt = result.resp.FetchedAt
+		}
+
+		for key, err := range result.resp.Rejected {
+			rejected[key] = err
+		}
+
+		for key, info := range result.resp.Infos {
+			if len(info.Source) == 0 {
+				info.Source = name
+			}
+			if current, ok := infos[key]; ok && !info.UpdateTime.After(current.UpdateTime) {
+				continue
+			}
+			infos[key] = info
+		}
+	}
+
+	if len(errMsgs) == len(c.sources) {
+		return nil, fmt.Errorf("%w: %s", ErrNoQuoteSource, strings.Join(errMsgs, "; "))
+	}
+
+	// a rejection only matters when no other source has a valid price
+	for key := range infos {
+		delete(rejected, key)
+	}
+
+	return &domain.GetQuotationsResponse{Infos: infos, FetchedAt: fetchedAt, Rejected: rejected}, nil
+}
@@ -0,0 +1,120 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// stubQuotes answers every request with the same response or error
+type stubQuotes struct {
+	resp *domain.GetQuotationsResponse
+	err  error
+}
+
+func (s *stubQuotes) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+	return s.resp, s.err
+}
+
+var (
+	now     = time.Now()
+	maxKey  = domain.QuoteKey{Exchange: constant.MAX, Pair: constant.USDTTWD}
+	bitoKey = domain.QuoteKey{Exchange: constant.BitoPro, Pair: constant.USDTTWD}
+)
+
+func quote(price string, updated time.Time) domain.QuotationInfo {
+	return domain.QuotationInfo{BuyPrice: decimal.RequireFromString(price), SellPrice: decimal.RequireFromString(price), UpdateTime: updated}
+}
+
+func TestFreshestQuoteWins(t *testing.T) {
+	older := &stubQuotes{resp: &domain.GetQuotationsResponse{
+		Infos: map[domain.QuoteKey]domain.QuotationInfo{
+			maxKey:  quote("32.1", now.Add(-time.Minute)),
+			bitoKey: quote("32.2", now),
+		},
+		FetchedAt: now.Add(-10 * time.Second),
+	}}
+	newer := &stubQuotes{resp: &domain.GetQuotationsResponse{
+		Infos: map[domain.QuoteKey]domain.QuotationInfo{
+			maxKey:  quote("32.3", now),
+			bitoKey: quote("32.4", now),
+		},
+		FetchedAt: now,
+	}}
+
+	resp, err := NewAggregateClient(Source{Name: "first", Repo: older}, Source{Name: "second", Repo: newer}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MAX is fresher in the second source, BitoPro ties and stays with the first
+	if v := resp.Infos[maxKey]; v.Source != "second" || !v.BuyPrice.Equal(decimal.RequireFromString("32.3")) {
+		t.Errorf("want the fresher MAX quote of second, got %+v", v)
+	}
+	if v := resp.Infos[bitoKey]; v.Source != "first" || !v.BuyPrice.Equal(decimal.RequireFromString("32.2")) {
+		t.Errorf("want the tie on BitoPro to go to first, got %+v", v)
+	}
+	if !resp.FetchedAt.Equal(now.Add(-10 * time.Second)) {
+		t.Errorf("want the snapshot as old as its oldest source, got %s", resp.FetchedAt)
+	}
+}
+
+func TestKeepsSourceName(t *testing.T) {
+	info := quote("32.1", now)
+	info.Source = "Max"
+	repo := &stubQuotes{resp: &domain.GetQuotationsResponse{Infos: map[domain.QuoteKey]domain.QuotationInfo{maxKey: info}}}
+
+	resp, err := NewAggregateClient(Source{Name: "comparison", Repo: repo}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := resp.Infos[maxKey]; v.Source != "Max" {
+		t.Errorf("want the source the quote came with, got %q", v.Source)
+	}
+}
+
+func TestFailingSources(t *testing.T) {
+	down := &stubQuotes{err: errors.New("connection refused")}
+	unsupported := &stubQuotes{err: domain.ErrPairUnsupported}
+	up := &stubQuotes{resp: &domain.GetQuotationsResponse{Infos: map[domain.QuoteKey]domain.QuotationInfo{maxKey: quote("32.1", now)}, FetchedAt: now}}
+
+	resp, err := NewAggregateClient(Source{Name: "down", Repo: down}, Source{Name: "up", Repo: up}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil || len(resp.Infos) != 1 {
+		t.Fatalf("want the quote of the source still up, got %+v, %v", resp, err)
+	}
+
+	_, err = NewAggregateClient(Source{Name: "down", Repo: down}, Source{Name: "unsupported", Repo: unsupported}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if !errors.Is(err, ErrNoQuoteSource) {
+		t.Fatalf("want ErrNoQuoteSource, got %v", err)
+	}
+}
+
+func TestRejectedQuotes(t *testing.T) {
+	invalid := &domain.QuoteError{Exchange: constant.MAX, Pair: constant.USDTTWD, Err: domain.ErrInvalidPrice}
+	stale := &domain.QuoteError{Exchange: constant.BitoPro, Pair: constant.USDTTWD, Err: domain.ErrStaleQuote}
+	rejecting := &stubQuotes{resp: &domain.GetQuotationsResponse{
+		Rejected: map[domain.QuoteKey]error{maxKey: invalid, bitoKey: stale},
+	}}
+	valid := &stubQuotes{resp: &domain.GetQuotationsResponse{Infos: map[domain.QuoteKey]domain.QuotationInfo{maxKey: quote("32.1", now)}}}
+
+	resp, err := NewAggregateClient(Source{Name: "rejecting", Repo: rejecting}, Source{Name: "valid", Repo: valid}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another source has a valid MAX price, nothing does for BitoPro
+	if _, ok := resp.Infos[maxKey]; !ok {
+		t.Errorf("want the valid MAX quote, got %+v", resp.Infos)
+	}
+	if _, ok := resp.Rejected[maxKey]; ok {
+		t.Errorf("want the MAX rejection dropped, got %+v", resp.Rejected)
+	}
+	if err := resp.Rejected[bitoKey]; !errors.Is(err, domain.ErrStaleQuote) {
+		t.Errorf("want the BitoPro rejection kept, got %v", err)
+	}
+}
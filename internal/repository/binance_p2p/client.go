@@ -0,0 +1,119 @@
+package binance_p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+type binanceP2PClient struct {
+	cli      transport.HttpClient
+	endpoint string
+}
+
+var _ domain.QuoteRepo = (*binanceP2PClient)(nil)
+
+// NewBinanceP2PClient quotes the best p2p advertisements of a pair, ads carry
+// no timestamp so the update time is the time of the search
+func NewBinanceP2PClient(cli transport.HttpClient, endpoint string) domain.QuoteRepo {
+	return &binanceP2PClient{
+		cli:      cli,
+		endpoint: endpoint,
+	}
+}
+
+func init() {
+	exchange.Register(constant.BinanceP2P, func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo {
+		return NewBinanceP2PClient(cli, cfg.BinanceP2PEndpoint)
+	})
+}
+
+var (
+	pathAdvSearch = "/bapi/c2c/v2/friendly/c2c/adv/search"
+
+	// trade types are from the taker's side, BUY lists the ads we can buy usdt from
+	tradeTypeBuy  = "BUY"
+	tradeTypeSell = "SELL"
+)
+
+func (c *binanceP2PClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	pair := req.Pair()
+	if pair.Quote != constant.TWD {
+		return nil, fmt.Errorf("%w: binance p2p only quotes TWD: %s", domain.ErrPairUnsupported, pair)
+	}
+	buyPrice, err := c.bestPrice(ctx, pair, tradeTypeBuy)
+	if err != nil {
+		return nil, err
+	}
+
+	sellPrice, err := c.bestPrice(ctx, pair, tradeTypeSell)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	infos := map[domain.QuoteKey]domain.QuotationInfo{
+		{Exchange: constant.BinanceP2P, Pair: pair}: {
+			BuyPrice:   buyPrice,
+			SellPrice:  sellPrice,
+			UpdateTime: now,
+		},
+	}
+
+	return &domain.GetQuotationsResponse{Infos: infos, FetchedAt: now}, nil
+}
+
+func (c *binanceP2PClient) bestPrice(ctx context.Context, pair constant.Pair, tradeType string) (decimal.Decimal, error) {
+
+	data, err := json.Marshal(&advSearchReqBody{
+		Asset:     string(pair.Base),
+		Fiat:      string(pair.Quote),
+		TradeType: tradeType,
+		Page:      1,
+		Rows:      1,
+		PayTypes:  []string{},
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// the search is read only, safe to retry even though it is a POST
+	url := fmt.Sprintf("%s%s", c.endpoint, pathAdvSearch)
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Body:   data,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Idempotent: true,
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	respBody := &advSearchRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return decimal.Zero, fmt.Errorf("%w: binance p2p: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	if !respBody.Success {
+		return decimal.Zero, fmt.Errorf("%w: binance p2p %s: %s", domain.ErrQuoteUnavailable, respBody.Code, respBody.Message)
+	}
+
+	if len(respBody.Data) == 0 {
+		return decimal.Zero, fmt.Errorf("%w: binance p2p: no %s advertisement", domain.ErrMalformedQuote, tradeType)
+	}
+
+	return respBody.Data[0].Adv.Price, nil
+}
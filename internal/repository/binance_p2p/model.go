@@ -0,0 +1,29 @@
+package binance_p2p
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type advSearchReqBody struct {
+	Asset     string   `json:"asset"`
+	Fiat      string   `json:"fiat"`
+	TradeType string   `json:"tradeType"`
+	Page      int      `json:"page"`
+	Rows      int      `json:"rows"`
+	PayTypes  []string `json:"payTypes"`
+}
+
+// advSearchRespBody is POST /bapi/c2c/v2/friendly/c2c/adv/search, ads come sorted best price first
+type advSearchRespBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+	Data    []struct {
+		Adv struct {
+			Price                decimal.Decimal `json:"price"`
+			TradableQuantity     decimal.Decimal `json:"tradableQuantity"`
+			MinSingleTransAmount decimal.Decimal `json:"minSingleTransAmount"`
+			MaxSingleTransAmount decimal.Decimal `json:"maxSingleTransAmount"`
+		} `json:"adv"`
+	} `json:"data"`
+}
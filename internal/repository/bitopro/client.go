@@ -0,0 +1,149 @@
+package bitopro
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+type bitoProClient struct {
+	cli      transport.HttpClient
+	endpoint string
+	maxAge   time.Duration
+}
+
+var (
+	_ domain.QuoteRepo = (*bitoProClient)(nil)
+	_ domain.DepthRepo = (*bitoProClient)(nil)
+)
+
+func NewBitoProClient(cli transport.HttpClient, endpoint string, maxAge time.Duration) domain.QuoteRepo {
+	return &bitoProClient{
+		cli:      cli,
+		endpoint: endpoint,
+		maxAge:   maxAge,
+	}
+}
+
+func init() {
+	exchange.Register(constant.BitoPro, func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo {
+		return NewBitoProClient(cli, cfg.BitoProEndpoint, cfg.StaleQuoteAgeOf(constant.BitoPro))
+	})
+}
+
+var (
+	pathOrderBook = "/v3/order-book/%s"
+)
+
+// pairID is the BitoPro id of a pair, e.g. usdt_twd
+func pairID(pair constant.Pair) string {
+	return strings.ToLower(string(pair.Base) + "_" + string(pair.Quote))
+}
+
+func (c *bitoProClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	pair := req.Pair()
+	respBody, err := c.getOrderBook(ctx, pair, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	updateTime := time.Now()
+	if respBody.Timestamp > 0 {
+		updateTime = time.UnixMilli(respBody.Timestamp)
+	}
+	info := domain.QuotationInfo{
+		BuyPrice:   respBody.Asks[0].Price,
+		SellPrice:  respBody.Bids[0].Price,
+		UpdateTime: updateTime,
+	}
+
+	return domain.NewQuoteResponse(domain.QuoteKey{Exchange: constant.BitoPro, Pair: pair}, info, c.maxAge), nil
+}
+
+func (c *bitoProClient) GetDepth(ctx context.Context, req domain.GetDepthRequest) (*domain.GetDepthResponse, error) {
+
+	respBody, err := c.getOrderBook(ctx, req.Pair(), req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.GetDepthResponse{
+		Exchange:   constant.BitoPro,
+		Pair:       req.Pair(),
+		UpdateTime: time.Now(),
+	}
+	if respBody.Timestamp > 0 {
+		resp.UpdateTime = time.UnixMilli(respBody.Timestamp)
+	}
+	for _, v := range respBody.Asks {
+		resp.Asks = append(resp.Asks, domain.DepthLevel{Price: v.Price, Volume: v.Amount})
+	}
+	for _, v := range respBody.Bids {
+		resp.Bids = append(resp.Bids, domain.DepthLevel{Price: v.Price, Volume: v.Amount})
+	}
+	resp.Sort(req.Limit)
+
+	return resp, nil
+}
+
+// getOrderBook returns a non empty order book, the api only accepts limit 1, 5, 10, 20, 30 or 50
+func (c *bitoProClient) getOrderBook(ctx context.Context, pair constant.Pair, limit int) (*orderBookRespBody, error) {
+
+	apiLimit := 50
+	for _, v := range []int{1, 5, 10, 20, 30, 50} {
+		if limit <= v {
+			apiLimit = v
+			break
+		}
+	}
+
+	url := fmt.Sprintf("%s%s", c.endpoint, fmt.Sprintf(pathOrderBook, pairID(pair)))
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+		Params: map[string]string{"limit": strconv.Itoa(apiLimit)},
+	})
+	if err != nil {
+		return nil, decodeError(httpResp, err)
+	}
+
+	respBody := &orderBookRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return nil, fmt.Errorf("%w: bitopro: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	if len(respBody.Error) > 0 {
+		return nil, fmt.Errorf("%w: bitopro: %s", domain.ErrQuoteUnavailable, respBody.Error)
+	}
+
+	if len(respBody.Asks) == 0 || len(respBody.Bids) == 0 {
+		return nil, fmt.Errorf("%w: bitopro: empty order book", domain.ErrMalformedQuote)
+	}
+
+	return respBody, nil
+}
+
+// decodeError maps the error body bitopro sends along with a 4xx status
+func decodeError(httpResp *transport.HttpResponse, err error) error {
+	if httpResp == nil {
+		return err
+	}
+
+	respBody := &orderBookRespBody{}
+	if jsonErr := json.Unmarshal(httpResp.Body, respBody); jsonErr != nil || len(respBody.Error) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%w: bitopro: %s (%s)", domain.ErrQuoteUnavailable, respBody.Error, err.Error())
+}
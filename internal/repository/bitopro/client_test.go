@@ -0,0 +1,91 @@
+package bitopro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+// newStandIn serves GET /v3/order-book/{pair}, books is the body of each known pair
+func newStandIn(t *testing.T, books map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pair string
+		if _, err := fmt.Sscanf(r.URL.Path, "/v3/order-book/%s", &pair); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, ok := books[pair]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid pair: ` + pair + `"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetQuotations(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	srv := newStandIn(t, map[string]string{
+		"usdc_twd": fmt.Sprintf(`{"asks":[{"price":"32.47","amount":"1500","count":1,"total":"1500"}],"bids":[{"price":"32.4","amount":"900","count":2,"total":"900"}],"timestamp":%d}`, now.UnixMilli()),
+	})
+	cli := NewBitoProClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	pair := constant.Pair{Base: constant.USDC, Quote: constant.TWD}
+	resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(pair))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := resp.Quotation(constant.BitoPro, pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.BuyPrice.Equal(decimal.RequireFromString("32.47")) || !info.SellPrice.Equal(decimal.RequireFromString("32.4")) {
+		t.Errorf("unexpected prices %s/%s", info.BuyPrice, info.SellPrice)
+	}
+	if !info.UpdateTime.Equal(now) {
+		t.Errorf("unexpected update time %s", info.UpdateTime)
+	}
+}
+
+func TestGetQuotationsErrors(t *testing.T) {
+	old := time.Now().Add(-time.Hour).UnixMilli()
+	srv := newStandIn(t, map[string]string{
+		"usdt_twd": fmt.Sprintf(`{"asks":[{"price":"32.5","amount":"1"}],"bids":[{"price":"32.4","amount":"1"}],"timestamp":%d}`, old),
+		"btc_twd":  `{"asks":[],"bids":[],"timestamp":0}`,
+		"eth_twd":  `<html>502 Bad Gateway</html>`,
+	})
+	cli := NewBitoProClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	for _, c := range []struct {
+		pair constant.Pair
+		want error
+	}{
+		{constant.USDTTWD, domain.ErrStaleQuote},
+		{constant.Pair{Base: constant.BTC, Quote: constant.TWD}, domain.ErrMalformedQuote},
+		{constant.Pair{Base: constant.ETH, Quote: constant.TWD}, domain.ErrMalformedQuote},
+		// bitopro lists no usdc/usdt market
+		{constant.Pair{Base: constant.USDC, Quote: constant.USDT}, domain.ErrQuoteUnavailable},
+	} {
+		// a stale quote is rejected in the response, the rest fail the call
+		resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(c.pair))
+		if err == nil {
+			_, err = resp.Quotation(constant.BitoPro, c.pair)
+		}
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: want %v, got %v", c.pair, c.want, err)
+		}
+	}
+}
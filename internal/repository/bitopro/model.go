@@ -0,0 +1,20 @@
+package bitopro
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type orderBookLevel struct {
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+	Count  int64           `json:"count"`
+	Total  decimal.Decimal `json:"total"`
+}
+
+// orderBookRespBody is GET /v3/order-book/{pair}, asks ascending and bids descending
+type orderBookRespBody struct {
+	Asks      []orderBookLevel `json:"asks"`
+	Bids      []orderBookLevel `json:"bids"`
+	Timestamp int64            `json:"timestamp"`
+	Error     string           `json:"error"`
+}
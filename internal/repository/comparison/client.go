@@ -27,19 +27,26 @@ func NewComparisonClient(cli transport.HttpClient) domain.QuoteRepo {
 	}
 }
 
+// SourceName identifies prices coming from the usdtwhere aggregator
+const SourceName = "usdtwhere"
+
 var (
 	pathComparison = "/v1/kgi/exchange-rates/comparison"
 )
 
 func (c *comparisonClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
 
+	// usdtwhere only compares usdt/twd rates
+	if req.Pair() != constant.USDTTWD {
+		return nil, fmt.Errorf("%w: usdtwhere: %s", domain.ErrPairUnsupported, req.Pair())
+	}
+
 	url := fmt.Sprintf("%s%s", c.endpoint, pathComparison)
 	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
 		Method: http.MethodGet,
 		URL:    url,
 	})
 	if err != nil {
-		log.Println("get quotations failed", err.Error())
 		return nil, err
 	}
 
@@ -50,21 +57,26 @@ func (c *comparisonClient) GetQuotations(ctx context.Context, req domain.GetQuot
 		return nil, err
 	}
 
-	infos := make(map[constant.Exchange]domain.QuotationInfo)
+	if respBody.Code != 0 && respBody.Code != http.StatusOK {
+		return nil, fmt.Errorf("%w: usdtwhere %d: %s", domain.ErrQuoteUnavailable, respBody.Code, respBody.Message)
+	}
+
+	infos := make(map[domain.QuoteKey]domain.QuotationInfo)
 
 	for _, v := range respBody.Data.Exchanges {
 		updateTime := time.UnixMilli(v.UpdateTime)
-		exchange := constant.Exchange(v.Name)
-		if len(exchange) == 0 {
+		exchange, ok := constant.LookupExchange(v.Name)
+		if !ok {
 			continue // skip unknown exchange
 		}
 
-		infos[exchange] = domain.QuotationInfo{
+		infos[domain.QuoteKey{Exchange: exchange, Pair: constant.USDTTWD}] = domain.QuotationInfo{
 			BuyPrice:   v.BuyRate,
 			SellPrice:  v.SellRate,
 			UpdateTime: updateTime,
+			Source:     SourceName,
 		}
 	}
 
-	return &domain.GetQuotationsResponse{Infos: infos}, nil
+	return &domain.GetQuotationsResponse{Infos: infos, FetchedAt: time.Now()}, nil
 }
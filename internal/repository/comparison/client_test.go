@@ -0,0 +1,63 @@
+package comparison
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+func newReplayClient(t *testing.T, cassette string) domain.QuoteRepo {
+	t.Helper()
+	cli, err := transport.NewRecorderClient("testdata/"+cassette, transport.RecorderReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewComparisonClient(cli)
+}
+
+func TestGetQuotations(t *testing.T) {
+	resp, err := newReplayClient(t, "comparison.json").GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unknown exchange is skipped
+	if len(resp.Infos) != 3 {
+		t.Fatalf("want 3 quotes, got %d", len(resp.Infos))
+	}
+
+	info, err := resp.Quotation(constant.Rybit, constant.USDTTWD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.BuyPrice.Equal(decimal.RequireFromString("32.38")) || !info.SellPrice.Equal(decimal.RequireFromString("32.2")) {
+		t.Errorf("unexpected rybit prices %s/%s", info.BuyPrice, info.SellPrice)
+	}
+	if !info.UpdateTime.Equal(time.UnixMilli(1700000005000)) {
+		t.Errorf("unexpected update time %s", info.UpdateTime)
+	}
+	if info.Source != SourceName {
+		t.Errorf("unexpected source %q", info.Source)
+	}
+}
+
+func TestGetQuotationsErrorCode(t *testing.T) {
+	_, err := newReplayClient(t, "comparison_error.json").GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if !errors.Is(err, domain.ErrQuoteUnavailable) {
+		t.Fatalf("want ErrQuoteUnavailable, got %v", err)
+	}
+}
+
+func TestGetQuotationsUnsupportedPair(t *testing.T) {
+	// the cassette has no btc interaction, the pair must be refused before any request
+	_, err := newReplayClient(t, "comparison.json").GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.Pair{Base: constant.BTC, Quote: constant.TWD}))
+	if !errors.Is(err, domain.ErrPairUnsupported) {
+		t.Fatalf("want ErrPairUnsupported, got %v", err)
+	}
+}
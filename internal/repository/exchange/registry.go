@@ -0,0 +1,83 @@
+// Package exchange keeps the set of direct exchange adapters, every adapter
+// package registers itself under its constant.Exchange in init.
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+type Factory func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo
+
+var (
+	lock     sync.RWMutex
+	adapters = make(map[constant.Exchange]Factory)
+)
+
+func Register(exchange constant.Exchange, factory Factory) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := adapters[exchange]; ok {
+		panic("exchange adapter registered twice: " + string(exchange))
+	}
+	adapters[exchange] = factory
+}
+
+// Exchanges returns every registered exchange sorted by name
+func Exchanges() []constant.Exchange {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	exchanges := make([]constant.Exchange, 0, len(adapters))
+	for k := range adapters {
+		exchanges = append(exchanges, k)
+	}
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i] < exchanges[j] })
+	return exchanges
+}
+
+// NewAdapters builds one QuoteRepo per registered exchange
+func NewAdapters(cli transport.HttpClient, cfg *config.ExchangeCfg) map[constant.Exchange]domain.QuoteRepo {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	repos := make(map[constant.Exchange]domain.QuoteRepo, len(adapters))
+	for k, factory := range adapters {
+		repos[k] = factory(cli, cfg)
+	}
+	return repos
+}
+
+type depthRouter struct {
+	adapters map[constant.Exchange]domain.QuoteRepo
+}
+
+var _ domain.DepthRepo = (*depthRouter)(nil)
+
+// NewDepthRouter sends GetDepth to the adapter of the requested exchange,
+// adapters that also implement domain.DepthRepo
+func NewDepthRouter(adapters map[constant.Exchange]domain.QuoteRepo) domain.DepthRepo {
+	return &depthRouter{adapters: adapters}
+}
+
+func (r *depthRouter) GetDepth(ctx context.Context, req domain.GetDepthRequest) (*domain.GetDepthResponse, error) {
+	adapter, ok := r.adapters[req.Exchange]
+	if !ok {
+		return nil, fmt.Errorf("%w: no adapter for %s", domain.ErrDepthUnsupported, req.Exchange)
+	}
+
+	depthRepo, ok := adapter.(domain.DepthRepo)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDepthUnsupported, req.Exchange)
+	}
+
+	return depthRepo.GetDepth(ctx, req)
+}
@@ -0,0 +1,356 @@
+// Package history keeps quote snapshots in an embedded bbolt file. Points live
+// in one bucket per resolution and one sub bucket per exchange and pair, keyed
+// by big endian unix nanoseconds so that cursors walk them in time order. Every
+// quote is rolled up into its minute and hour buckets as it is recorded.
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltHistory struct {
+	db              *bolt.DB
+	rawRetention    time.Duration
+	minuteRetention time.Duration
+}
+
+var _ domain.HistoryRepo = (*boltHistory)(nil)
+
+// resolutions from the finest, each one holds every quote the next one does
+var resolutions = []domain.Resolution{domain.ResolutionRaw, domain.ResolutionMinute, domain.ResolutionHour}
+
+// NewBoltHistory opens or creates the store at path. Raw points are dropped after
+// rawRetention and 1 minute OHLC after minuteRetention, hourly OHLC are kept for good.
+func NewBoltHistory(path string, rawRetention time.Duration, minuteRetention time.Duration) (domain.HistoryRepo, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open history %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, v := range resolutions {
+			if _, err := tx.CreateBucketIfNotExists([]byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltHistory{db: db, rawRetention: rawRetention, minuteRetention: minuteRetention}, nil
+}
+
+// Close releases the file lock, the store must not be used afterwards
+func (h *boltHistory) Close() error {
+	return h.db.Close()
+}
+
+// storedPoint is the value of a point, its time, exchange and pair are in the keys
+type storedPoint struct {
+	Source     string             `json:"s,omitempty"`
+	UpdateTime int64              `json:"u"`
+	Buy        [4]decimal.Decimal `json:"b"`
+	Sell       [4]decimal.Decimal `json:"a"`
+	Count      int                `json:"n"`
+}
+
+func seriesKey(exchange constant.Exchange, pair constant.Pair) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", exchange, pair.Base, pair.Quote))
+}
+
+func parseSeriesKey(key []byte) (constant.Exchange, constant.Pair, bool) {
+	parts := strings.Split(string(key), "|")
+	if len(parts) != 3 {
+		return "", constant.Pair{}, false
+	}
+	return constant.Exchange(parts[0]), constant.Pair{Base: constant.Symbol(parts[1]), Quote: constant.Symbol(parts[2])}, true
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+func parseTimeKey(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key)))
+}
+
+func encodePoint(p domain.HistoryPoint) ([]byte, error) {
+	return json.Marshal(&storedPoint{
+		Source:     p.Source,
+		UpdateTime: p.UpdateTime.UnixMilli(),
+		Buy:        [4]decimal.Decimal{p.Buy.Open, p.Buy.High, p.Buy.Low, p.Buy.Close},
+		Sell:       [4]decimal.Decimal{p.Sell.Open, p.Sell.High, p.Sell.Low, p.Sell.Close},
+		Count:      p.Count,
+	})
+}
+
+func decodePoint(series []byte, resolution domain.Resolution, key []byte, value []byte) (domain.HistoryPoint, error) {
+	exchange, pair, ok := parseSeriesKey(series)
+	if !ok {
+		return domain.HistoryPoint{}, fmt.Errorf("history: malformed series %q", series)
+	}
+
+	stored := &storedPoint{}
+	if err := json.Unmarshal(value, stored); err != nil {
+		return domain.HistoryPoint{}, fmt.Errorf("history: malformed point in %s: %w", series, err)
+	}
+
+	return domain.HistoryPoint{
+		Exchange:   exchange,
+		Pair:       pair,
+		Time:       parseTimeKey(key),
+		Resolution: resolution,
+		Source:     stored.Source,
+		UpdateTime: time.UnixMilli(stored.UpdateTime),
+		Buy:        domain.OHLC{Open: stored.Buy[0], High: stored.Buy[1], Low: stored.Buy[2], Close: stored.Buy[3]},
+		Sell:       domain.OHLC{Open: stored.Sell[0], High: stored.Sell[1], Low: stored.Sell[2], Close: stored.Sell[3]},
+		Count:      stored.Count,
+	}, nil
+}
+
+func (h *boltHistory) RecordQuotations(ctx context.Context, snapshot *domain.GetQuotationsResponse) error {
+	if snapshot == nil || len(snapshot.Infos) == 0 {
+		return nil
+	}
+
+	fetchedAt := snapshot.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	return h.db.Update(func(tx *bolt.Tx) error {
+		raw := tx.Bucket([]byte(domain.ResolutionRaw))
+		for key, info := range snapshot.Infos {
+			name := seriesKey(key.Exchange, key.Pair)
+			series, err := raw.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+
+			point := domain.HistoryPoint{
+				Time:       fetchedAt,
+				Source:     info.Source,
+				UpdateTime: info.UpdateTime,
+				Buy:        domain.NewOHLC(info.BuyPrice),
+				Sell:       domain.NewOHLC(info.SellPrice),
+				Count:      1,
+			}
+			value, err := encodePoint(point)
+			if err != nil {
+				return err
+			}
+
+			// a cached snapshot recorded twice overwrites itself and is rolled up once
+			recorded := series.Get(timeKey(fetchedAt)) != nil
+			if err := series.Put(timeKey(fetchedAt), value); err != nil {
+				return err
+			}
+			if recorded {
+				continue
+			}
+
+			// the coarser buckets are kept up to date so that reading them never decodes raw points
+			for _, resolution := range resolutions[1:] {
+				point.Time, point.Resolution = fetchedAt.Truncate(resolution.Duration()), resolution
+				if err := mergePoint(tx.Bucket([]byte(resolution)), name, point); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// mergePoint adds point to the one stored in its bucket of the series name
+func mergePoint(bucket *bolt.Bucket, name []byte, point domain.HistoryPoint) error {
+	series, err := bucket.CreateBucketIfNotExists(name)
+	if err != nil {
+		return err
+	}
+
+	key := timeKey(point.Time)
+	if existing := series.Get(key); existing != nil {
+		earlier, err := decodePoint(name, point.Resolution, key, existing)
+		if err != nil {
+			return err
+		}
+		point = earlier.Merge(point)
+	}
+
+	value, err := encodePoint(point)
+	if err != nil {
+		return err
+	}
+	return series.Put(key, value)
+}
+
+// GetHistory reads the coarsest bucket not coarser than the resolution asked for, and
+// the coarser ones only for the time before the points it still keeps
+func (h *boltHistory) GetHistory(ctx context.Context, req domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+	pair := req.Pair()
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	first := 0
+	for i, v := range resolutions {
+		if v.Duration() <= req.Resolution.Duration() {
+			first = i
+		}
+	}
+
+	series := make(map[constant.Exchange][]domain.HistoryPoint)
+	err := h.db.View(func(tx *bolt.Tx) error {
+		// the earliest point read of each series, a coarser bucket stops before it
+		until := make(map[string]time.Time)
+		for _, resolution := range resolutions[first:] {
+			bucket := tx.Bucket([]byte(resolution))
+			// a bucket starting before From still covers part of the range
+			from := req.From.Truncate(resolution.Duration())
+
+			err := bucket.ForEach(func(name, _ []byte) error {
+				exchange, p, ok := parseSeriesKey(name)
+				if !ok || p != pair || (len(req.Exchange) > 0 && exchange != req.Exchange) {
+					return nil
+				}
+
+				end, bounded := until[string(name)]
+				if bounded {
+					end = end.Truncate(resolution.Duration())
+				}
+
+				points := []domain.HistoryPoint{}
+				c := bucket.Bucket(name).Cursor()
+				for k, v := c.Seek(timeKey(from)); k != nil && !parseTimeKey(k).After(to); k, v = c.Next() {
+					if bounded && !parseTimeKey(k).Before(end) {
+						break
+					}
+					point, err := decodePoint(name, resolution, k, v)
+					if err != nil {
+						return err
+					}
+					points = append(points, point)
+				}
+				if len(points) > 0 {
+					until[string(name)] = points[0].Time
+					series[exchange] = append(series[exchange], points...)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges := make([]constant.Exchange, 0, len(series))
+	for k := range series {
+		exchanges = append(exchanges, k)
+	}
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i] < exchanges[j] })
+
+	resp := &domain.GetHistoryResponse{}
+	for _, exchange := range exchanges {
+		points := series[exchange]
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		resp.Points = append(resp.Points, downsample(points, req.Resolution)...)
+	}
+	return resp, nil
+}
+
+// downsample merges time sorted points of one series into buckets of resolution,
+// points already coarser than resolution are passed through
+func downsample(points []domain.HistoryPoint, resolution domain.Resolution) []domain.HistoryPoint {
+	size := resolution.Duration()
+	if size <= 0 {
+		return points
+	}
+
+	merged := []domain.HistoryPoint{}
+	for _, v := range points {
+		if v.Resolution.Duration() > size {
+			merged = append(merged, v)
+			continue
+		}
+
+		start := v.Time.Truncate(size)
+		if n := len(merged); n > 0 && merged[n-1].Resolution == resolution && merged[n-1].Time.Equal(start) {
+			merged[n-1] = merged[n-1].Merge(v)
+			continue
+		}
+		v.Time, v.Resolution = start, resolution
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+func (h *boltHistory) Compact(ctx context.Context, now time.Time) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		if h.rawRetention > 0 {
+			if err := prune(tx, domain.ResolutionRaw, domain.ResolutionMinute, now.Add(-h.rawRetention)); err != nil {
+				return err
+			}
+		}
+		if h.minuteRetention > 0 {
+			if err := prune(tx, domain.ResolutionMinute, domain.ResolutionHour, now.Add(-h.minuteRetention)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// prune deletes the points of from in the buckets of to that ended before cutoff,
+// those buckets already hold them. Whole buckets go so that what from keeps never
+// shares a bucket of to with what it dropped.
+func prune(tx *bolt.Tx, from domain.Resolution, to domain.Resolution, cutoff time.Time) error {
+	src := tx.Bucket([]byte(from))
+	end := timeKey(cutoff.Truncate(to.Duration()))
+
+	names := [][]byte{}
+	if err := src.ForEach(func(name, _ []byte) error {
+		names = append(names, append([]byte{}, name...))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		series := src.Bucket(name)
+		keys := [][]byte{}
+
+		c := series.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		for _, k := range keys {
+			if err := series.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+)
+
+var t0 = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+type testPrice struct {
+	offset time.Duration
+	price  string
+}
+
+// newTestHistory records a BitoPro USDT/TWD price at each offset from t0, in order
+func newTestHistory(t *testing.T, rawRetention time.Duration, minuteRetention time.Duration, prices []testPrice) *boltHistory {
+	repo, err := NewBoltHistory(filepath.Join(t.TempDir(), "history.db"), rawRetention, minuteRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := repo.(*boltHistory)
+	t.Cleanup(func() { h.Close() })
+
+	for _, v := range prices {
+		offset, price := v.offset, v.price
+		err := h.RecordQuotations(context.Background(), &domain.GetQuotationsResponse{
+			Infos: map[domain.QuoteKey]domain.QuotationInfo{
+				{Exchange: constant.BitoPro, Pair: constant.USDTTWD}: {
+					BuyPrice:   decimal.RequireFromString(price),
+					SellPrice:  decimal.RequireFromString(price),
+					UpdateTime: t0.Add(offset),
+				},
+			},
+			FetchedAt: t0.Add(offset),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+var testPrices = []testPrice{
+	{10 * time.Second, "31.0"},
+	{50 * time.Second, "31.4"},
+	{70 * time.Second, "30.9"},
+	{time.Hour + time.Minute, "31.2"},
+	{time.Hour + time.Minute + 5e9, "31.3"},
+	{time.Hour + 2*time.Minute + 1e9, "31.1"},
+}
+
+type wantPoint struct {
+	time       time.Duration
+	resolution domain.Resolution
+	count      int
+	ohlc       [4]string
+}
+
+func checkPoints(t *testing.T, points []domain.HistoryPoint, want []wantPoint) {
+	t.Helper()
+	if len(points) != len(want) {
+		t.Fatalf("want %d points, got %+v", len(want), points)
+	}
+	for i, w := range want {
+		p := points[i]
+		if !p.Time.Equal(t0.Add(w.time)) || p.Resolution != w.resolution || p.Count != w.count {
+			t.Errorf("point %d: want %s %s x%d, got %s %s x%d", i, t0.Add(w.time), w.resolution, w.count, p.Time, p.Resolution, p.Count)
+		}
+		got := [4]decimal.Decimal{p.Buy.Open, p.Buy.High, p.Buy.Low, p.Buy.Close}
+		for j, v := range w.ohlc {
+			if !got[j].Equal(decimal.RequireFromString(v)) {
+				t.Errorf("point %d: want buy OHLC %v, got %v", i, w.ohlc, got)
+				break
+			}
+		}
+	}
+}
+
+func TestGetHistoryResolutions(t *testing.T) {
+	h := newTestHistory(t, 0, 0, testPrices)
+
+	tests := []struct {
+		name       string
+		resolution domain.Resolution
+		from       time.Duration
+		to         time.Duration
+		want       []wantPoint
+	}{
+		{
+			name:       "raw",
+			resolution: domain.ResolutionRaw,
+			to:         2 * time.Hour,
+			want: []wantPoint{
+				{10 * time.Second, domain.ResolutionRaw, 1, [4]string{"31.0", "31.0", "31.0", "31.0"}},
+				{50 * time.Second, domain.ResolutionRaw, 1, [4]string{"31.4", "31.4", "31.4", "31.4"}},
+				{70 * time.Second, domain.ResolutionRaw, 1, [4]string{"30.9", "30.9", "30.9", "30.9"}},
+				{time.Hour + time.Minute, domain.ResolutionRaw, 1, [4]string{"31.2", "31.2", "31.2", "31.2"}},
+				{time.Hour + time.Minute + 5e9, domain.ResolutionRaw, 1, [4]string{"31.3", "31.3", "31.3", "31.3"}},
+				{time.Hour + 2*time.Minute + 1e9, domain.ResolutionRaw, 1, [4]string{"31.1", "31.1", "31.1", "31.1"}},
+			},
+		},
+		{
+			name:       "minute",
+			resolution: domain.ResolutionMinute,
+			to:         2 * time.Hour,
+			want: []wantPoint{
+				{0, domain.ResolutionMinute, 2, [4]string{"31.0", "31.4", "31.0", "31.4"}},
+				{time.Minute, domain.ResolutionMinute, 1, [4]string{"30.9", "30.9", "30.9", "30.9"}},
+				{time.Hour + time.Minute, domain.ResolutionMinute, 2, [4]string{"31.2", "31.3", "31.2", "31.3"}},
+				{time.Hour + 2*time.Minute, domain.ResolutionMinute, 1, [4]string{"31.1", "31.1", "31.1", "31.1"}},
+			},
+		},
+		{
+			name:       "hour",
+			resolution: domain.ResolutionHour,
+			to:         2 * time.Hour,
+			want: []wantPoint{
+				{0, domain.ResolutionHour, 3, [4]string{"31.0", "31.4", "30.9", "30.9"}},
+				{time.Hour, domain.ResolutionHour, 3, [4]string{"31.2", "31.3", "31.1", "31.1"}},
+			},
+		},
+		{
+			// the bucket starting before From covers part of the range, To is inclusive
+			name:       "minute buckets on the edges",
+			resolution: domain.ResolutionMinute,
+			from:       30 * time.Second,
+			to:         time.Minute,
+			want: []wantPoint{
+				{0, domain.ResolutionMinute, 2, [4]string{"31.0", "31.4", "31.0", "31.4"}},
+				{time.Minute, domain.ResolutionMinute, 1, [4]string{"30.9", "30.9", "30.9", "30.9"}},
+			},
+		},
+		{
+			name:       "raw points on the edges",
+			resolution: domain.ResolutionRaw,
+			from:       50 * time.Second,
+			to:         70 * time.Second,
+			want: []wantPoint{
+				{50 * time.Second, domain.ResolutionRaw, 1, [4]string{"31.4", "31.4", "31.4", "31.4"}},
+				{70 * time.Second, domain.ResolutionRaw, 1, [4]string{"30.9", "30.9", "30.9", "30.9"}},
+			},
+		},
+		{
+			name:       "hour bucket starting at From",
+			resolution: domain.ResolutionHour,
+			from:       time.Hour,
+			to:         2 * time.Hour,
+			want: []wantPoint{
+				{time.Hour, domain.ResolutionHour, 3, [4]string{"31.2", "31.3", "31.1", "31.1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.GetHistory(context.Background(), domain.GetHistoryRequest{
+				From:       t0.Add(tt.from),
+				To:         t0.Add(tt.to),
+				Resolution: tt.resolution,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPoints(t, resp.Points, tt.want)
+		})
+	}
+}
+
+func TestRecordQuotationsTwice(t *testing.T) {
+	h := newTestHistory(t, 0, 0, []testPrice{{10 * time.Second, "31.0"}})
+
+	// a cached snapshot comes back with the same FetchedAt
+	err := h.RecordQuotations(context.Background(), &domain.GetQuotationsResponse{
+		Infos: map[domain.QuoteKey]domain.QuotationInfo{
+			{Exchange: constant.BitoPro, Pair: constant.USDTTWD}: {BuyPrice: decimal.RequireFromString("31.0"), SellPrice: decimal.RequireFromString("31.0")},
+		},
+		FetchedAt: t0.Add(10 * time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := h.GetHistory(context.Background(), domain.GetHistoryRequest{From: t0, To: t0.Add(time.Hour), Resolution: domain.ResolutionHour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPoints(t, resp.Points, []wantPoint{{0, domain.ResolutionHour, 1, [4]string{"31.0", "31.0", "31.0", "31.0"}}})
+}
+
+// storedTimes lists the keys of the BitoPro USDT/TWD series in the bucket of resolution
+func storedTimes(t *testing.T, h *boltHistory, resolution domain.Resolution) []time.Duration {
+	times := []time.Duration{}
+	err := h.db.View(func(tx *bolt.Tx) error {
+		series := tx.Bucket([]byte(resolution)).Bucket(seriesKey(constant.BitoPro, constant.USDTTWD))
+		if series == nil {
+			return nil
+		}
+		return series.ForEach(func(k, _ []byte) error {
+			times = append(times, parseTimeKey(k).Sub(t0))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return times
+}
+
+func equalTimes(a []time.Duration, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompact(t *testing.T) {
+	h := newTestHistory(t, time.Hour, 24*time.Hour, testPrices)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		now     time.Duration
+		raw     []time.Duration
+		minute  []time.Duration
+		hour    []time.Duration
+		history []wantPoint
+	}{
+		{
+			name:   "nothing past retention",
+			now:    time.Hour,
+			raw:    []time.Duration{10 * time.Second, 50 * time.Second, 70 * time.Second, time.Hour + time.Minute, time.Hour + time.Minute + 5e9, time.Hour + 2*time.Minute + 1e9},
+			minute: []time.Duration{0, time.Minute, time.Hour + time.Minute, time.Hour + 2*time.Minute},
+			hour:   []time.Duration{0, time.Hour},
+		},
+		{
+			// the cutoff falls inside the 11:01 minute, which is kept whole
+			name:   "raw points past retention",
+			now:    2*time.Hour + time.Minute + 30*time.Second,
+			raw:    []time.Duration{time.Hour + time.Minute, time.Hour + time.Minute + 5e9, time.Hour + 2*time.Minute + 1e9},
+			minute: []time.Duration{0, time.Minute, time.Hour + time.Minute, time.Hour + 2*time.Minute},
+			hour:   []time.Duration{0, time.Hour},
+			history: []wantPoint{
+				{0, domain.ResolutionMinute, 2, [4]string{"31.0", "31.4", "31.0", "31.4"}},
+				{time.Minute, domain.ResolutionMinute, 1, [4]string{"30.9", "30.9", "30.9", "30.9"}},
+				{time.Hour + time.Minute, domain.ResolutionRaw, 1, [4]string{"31.2", "31.2", "31.2", "31.2"}},
+				{time.Hour + time.Minute + 5e9, domain.ResolutionRaw, 1, [4]string{"31.3", "31.3", "31.3", "31.3"}},
+				{time.Hour + 2*time.Minute + 1e9, domain.ResolutionRaw, 1, [4]string{"31.1", "31.1", "31.1", "31.1"}},
+			},
+		},
+		{
+			// the cutoff falls inside the 11:00 hour, which is kept whole
+			name:   "minute points past retention",
+			now:    25*time.Hour + 30*time.Minute,
+			raw:    []time.Duration{},
+			minute: []time.Duration{time.Hour + time.Minute, time.Hour + 2*time.Minute},
+			hour:   []time.Duration{0, time.Hour},
+			history: []wantPoint{
+				{0, domain.ResolutionHour, 3, [4]string{"31.0", "31.4", "30.9", "30.9"}},
+				{time.Hour + time.Minute, domain.ResolutionMinute, 2, [4]string{"31.2", "31.3", "31.2", "31.3"}},
+				{time.Hour + 2*time.Minute, domain.ResolutionMinute, 1, [4]string{"31.1", "31.1", "31.1", "31.1"}},
+			},
+		},
+		{
+			name:   "hour points are kept for good",
+			now:    1000 * time.Hour,
+			raw:    []time.Duration{},
+			minute: []time.Duration{},
+			hour:   []time.Duration{0, time.Hour},
+			history: []wantPoint{
+				{0, domain.ResolutionHour, 3, [4]string{"31.0", "31.4", "30.9", "30.9"}},
+				{time.Hour, domain.ResolutionHour, 3, [4]string{"31.2", "31.3", "31.1", "31.1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := h.Compact(ctx, t0.Add(tt.now)); err != nil {
+				t.Fatal(err)
+			}
+			for resolution, want := range map[domain.Resolution][]time.Duration{domain.ResolutionRaw: tt.raw, domain.ResolutionMinute: tt.minute, domain.ResolutionHour: tt.hour} {
+				if got := storedTimes(t, h, resolution); !equalTimes(got, want) {
+					t.Errorf("%s: want %v kept, got %v", resolution, want, got)
+				}
+			}
+
+			if tt.history == nil {
+				return
+			}
+			// a raw request falls back to the coarser buckets for what was dropped
+			resp, err := h.GetHistory(ctx, domain.GetHistoryRequest{From: t0, To: t0.Add(2 * time.Hour), Resolution: domain.ResolutionRaw})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPoints(t, resp.Points, tt.history)
+		})
+	}
+}
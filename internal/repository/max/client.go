@@ -0,0 +1,135 @@
+package max
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+type maxClient struct {
+	cli      transport.HttpClient
+	endpoint string
+	maxAge   time.Duration
+}
+
+var (
+	_ domain.QuoteRepo = (*maxClient)(nil)
+	_ domain.DepthRepo = (*maxClient)(nil)
+)
+
+// NewMaxClient reads quotes from MAX's public rest api at endpoint, e.g.
+// https://max-api.maicoin.com or a local stand-in server, tickers older than
+// maxAge are rejected with domain.ErrStaleQuote
+func NewMaxClient(cli transport.HttpClient, endpoint string, maxAge time.Duration) domain.QuoteRepo {
+	return &maxClient{
+		cli:      cli,
+		endpoint: endpoint,
+		maxAge:   maxAge,
+	}
+}
+
+func init() {
+	exchange.Register(constant.MAX, func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo {
+		return NewMaxClient(cli, cfg.MaxEndpoint, cfg.StaleQuoteAgeOf(constant.MAX))
+	})
+}
+
+var (
+	pathTicker = "/api/v2/tickers/%s"
+	pathDepth  = "/api/v2/depth"
+)
+
+// market is the MAX market id of a pair, e.g. usdttwd
+func market(pair constant.Pair) string {
+	return strings.ToLower(string(pair.Base) + string(pair.Quote))
+}
+
+func (c *maxClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	pair := req.Pair()
+	url := fmt.Sprintf("%s%s", c.endpoint, fmt.Sprintf(pathTicker, market(pair)))
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+	})
+	if err != nil {
+		return nil, decodeError(httpResp, err)
+	}
+
+	respBody := &tickerRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return nil, fmt.Errorf("%w: max: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	if respBody.At == 0 {
+		return nil, fmt.Errorf("%w: max ticker %s has no timestamp", domain.ErrMalformedQuote, market(pair))
+	}
+
+	// we buy at the best ask and sell at the best bid
+	info := domain.QuotationInfo{
+		BuyPrice:   respBody.Sell,
+		SellPrice:  respBody.Buy,
+		UpdateTime: time.Unix(respBody.At, 0),
+	}
+
+	return domain.NewQuoteResponse(domain.QuoteKey{Exchange: constant.MAX, Pair: pair}, info, c.maxAge), nil
+}
+
+func (c *maxClient) GetDepth(ctx context.Context, req domain.GetDepthRequest) (*domain.GetDepthResponse, error) {
+
+	url := fmt.Sprintf("%s%s", c.endpoint, pathDepth)
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+		Params: map[string]string{
+			"market": market(req.Pair()),
+			"limit":  strconv.Itoa(req.Limit),
+		},
+	})
+	if err != nil {
+		return nil, decodeError(httpResp, err)
+	}
+
+	respBody := &depthRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return nil, fmt.Errorf("%w: max depth: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	resp := &domain.GetDepthResponse{
+		Exchange:   constant.MAX,
+		Pair:       req.Pair(),
+		UpdateTime: time.Unix(respBody.Timestamp, 0),
+	}
+	for _, v := range respBody.Asks {
+		resp.Asks = append(resp.Asks, domain.DepthLevel{Price: v[0], Volume: v[1]})
+	}
+	for _, v := range respBody.Bids {
+		resp.Bids = append(resp.Bids, domain.DepthLevel{Price: v[0], Volume: v[1]})
+	}
+	resp.Sort(req.Limit)
+
+	return resp, nil
+}
+
+func decodeError(httpResp *transport.HttpResponse, err error) error {
+	if httpResp == nil {
+		return err
+	}
+
+	respBody := &errorRespBody{}
+	if jsonErr := json.Unmarshal(httpResp.Body, respBody); jsonErr != nil || respBody.Error == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: max %d: %s (%s)", domain.ErrQuoteUnavailable, respBody.Error.Code, respBody.Error.Message, err.Error())
+}
@@ -0,0 +1,107 @@
+package max
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+// newStandIn serves the MAX rest api subset the client calls
+func newStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/tickers/usdttwd", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"at":1700000000,"buy":"32.41","sell":"32.45","open":"32.3","low":"32.2","high":"32.5","last":"32.43","vol":"1234567.89","vol_in_btc":"20.1"}`))
+	})
+	mux.HandleFunc("/api/v2/tickers/btctwd", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>502 Bad Gateway</html>`))
+	})
+	mux.HandleFunc("/api/v2/tickers/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":2004,"message":"market does not exist"}}`))
+	})
+	mux.HandleFunc("/api/v2/depth", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("market") != "btctwd" {
+			t.Errorf("unexpected market %q", r.URL.Query().Get("market"))
+		}
+		w.Write([]byte(`{"timestamp":1700000000,"last_update_version":1,"asks":[["1100000","0.2"],["1090000","0.1"]],"bids":[["1080000","0.5"],["1085000","0.3"]]}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetQuotations(t *testing.T) {
+	srv := newStandIn(t)
+	cli := NewMaxClient(transport.NewHttpClient(), srv.URL, 0)
+
+	resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := resp.Quotation(constant.MAX, constant.USDTTWD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// we buy at the ask and sell at the bid
+	if !info.BuyPrice.Equal(decimal.RequireFromString("32.45")) || !info.SellPrice.Equal(decimal.RequireFromString("32.41")) {
+		t.Errorf("unexpected prices %s/%s", info.BuyPrice, info.SellPrice)
+	}
+	if !info.UpdateTime.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected update time %s", info.UpdateTime)
+	}
+}
+
+func TestGetQuotationsUnknownMarket(t *testing.T) {
+	srv := newStandIn(t)
+	cli := NewMaxClient(transport.NewHttpClient(), srv.URL, 0)
+
+	_, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.Pair{Base: constant.ETH, Quote: constant.USDC}))
+	if !errors.Is(err, domain.ErrQuoteUnavailable) {
+		t.Fatalf("want ErrQuoteUnavailable, got %v", err)
+	}
+}
+
+func TestGetQuotationsStaleAndMalformed(t *testing.T) {
+	srv := newStandIn(t)
+	cli := NewMaxClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	// the stand-in ticker is from 2023
+	resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resp.Quotation(constant.MAX, constant.USDTTWD); !errors.Is(err, domain.ErrStaleQuote) {
+		t.Errorf("want the old ticker rejected as stale, got %v", err)
+	}
+
+	_, err = cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.Pair{Base: constant.BTC, Quote: constant.TWD}))
+	if !errors.Is(err, domain.ErrMalformedQuote) {
+		t.Errorf("want ErrMalformedQuote, got %v", err)
+	}
+}
+
+func TestGetDepth(t *testing.T) {
+	srv := newStandIn(t)
+	cli := NewMaxClient(transport.NewHttpClient(), srv.URL, 0).(domain.DepthRepo)
+
+	resp, err := cli.GetDepth(context.Background(), domain.GetDepthRequest{Base: constant.BTC, Quote: constant.TWD, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the best levels first, cut to the limit
+	if len(resp.Asks) != 1 || !resp.Asks[0].Price.Equal(decimal.NewFromInt(1090000)) {
+		t.Errorf("unexpected asks %+v", resp.Asks)
+	}
+	if len(resp.Bids) != 1 || !resp.Bids[0].Price.Equal(decimal.NewFromInt(1085000)) {
+		t.Errorf("unexpected bids %+v", resp.Bids)
+	}
+}
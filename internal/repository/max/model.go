@@ -0,0 +1,53 @@
+package max
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// tickerRespBody is GET /api/v2/tickers/{market}, buy is the best bid and sell is the best ask
+type tickerRespBody struct {
+	At   int64           `json:"at"`
+	Buy  decimal.Decimal `json:"buy"`
+	Sell decimal.Decimal `json:"sell"`
+	Last decimal.Decimal `json:"last"`
+	Vol  decimal.Decimal `json:"vol"`
+}
+
+type errorRespBody struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// depthRespBody is GET /api/v2/depth, levels are [price, volume] pairs
+type depthRespBody struct {
+	Timestamp int64                `json:"timestamp"`
+	Asks      [][2]decimal.Decimal `json:"asks"`
+	Bids      [][2]decimal.Decimal `json:"bids"`
+}
+
+// streamSubReqBody subscribes to websocket channels
+type streamSubReqBody struct {
+	Action        string             `json:"action"`
+	Subscriptions []streamSubChannel `json:"subscriptions"`
+	ID            string             `json:"id"`
+}
+
+type streamSubChannel struct {
+	Channel string `json:"channel"`
+	Market  string `json:"market"`
+	Depth   int    `json:"depth,omitempty"`
+}
+
+// streamEvent is a websocket message, book snapshots carry whole sides and
+// updates only the changed levels, a zero volume removes a level
+type streamEvent struct {
+	Channel   string               `json:"c"`
+	Event     string               `json:"e"`
+	Market    string               `json:"M"`
+	Asks      [][2]decimal.Decimal `json:"a"`
+	Bids      [][2]decimal.Decimal `json:"b"`
+	Timestamp int64                `json:"T"`
+	Errors    []string             `json:"E"`
+}
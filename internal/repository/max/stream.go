@@ -0,0 +1,140 @@
+package max
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+// StreamSourceName identifies prices coming from the MAX websocket
+const StreamSourceName = "max-stream"
+
+type MaxStream struct {
+	cfg   transport.WebSocketConfig
+	pairs map[string]constant.Pair
+
+	lock  sync.Mutex
+	books map[string]*streamBook
+}
+
+// streamBook is the local copy of one market, keyed by price string
+type streamBook struct {
+	asks map[string]domain.DepthLevel
+	bids map[string]domain.DepthLevel
+}
+
+// NewMaxStream follows the order books of pairs on the MAX websocket at endpoint,
+// e.g. wss://max-stream.maicoin.com/ws or a local stand-in server
+func NewMaxStream(endpoint string, pairs []constant.Pair) *MaxStream {
+	s := &MaxStream{
+		cfg:   transport.DefaultWebSocketConfig(endpoint),
+		pairs: make(map[string]constant.Pair, len(pairs)),
+		books: make(map[string]*streamBook),
+	}
+
+	sub := &streamSubReqBody{Action: "sub", ID: "telegram-quote-bot"}
+	for _, v := range pairs {
+		s.pairs[market(v)] = v
+		sub.Subscriptions = append(sub.Subscriptions, streamSubChannel{Channel: "book", Market: market(v), Depth: 5})
+	}
+	s.cfg.Subscriptions = []interface{}{sub}
+	return s
+}
+
+// Run pushes the top of book of every pair into updater until ctx is done
+func (s *MaxStream) Run(ctx context.Context, updater domain.QuoteUpdater) error {
+	return transport.RunWebSocket(ctx, s.cfg, func(msg []byte) {
+		s.handle(msg, updater)
+	}, func() {
+		// a new connection starts from fresh snapshots
+		s.lock.Lock()
+		s.books = make(map[string]*streamBook)
+		s.lock.Unlock()
+	})
+}
+
+func (s *MaxStream) handle(msg []byte, updater domain.QuoteUpdater) {
+	event := &streamEvent{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		log.Println("max stream: malformed message: ", err.Error())
+		return
+	}
+
+	if event.Event == "error" {
+		log.Println("max stream: ", strings.Join(event.Errors, "; "))
+		return
+	}
+
+	pair, ok := s.pairs[event.Market]
+	if event.Channel != "book" || !ok {
+		return
+	}
+
+	s.lock.Lock()
+	book, ok := s.books[event.Market]
+	switch {
+	case event.Event == "snapshot":
+		book = &streamBook{asks: make(map[string]domain.DepthLevel), bids: make(map[string]domain.DepthLevel)}
+		s.books[event.Market] = book
+	case event.Event != "update" || !ok:
+		// an update before its snapshot cannot be applied
+		s.lock.Unlock()
+		return
+	}
+	book.apply(book.asks, event.Asks)
+	book.apply(book.bids, event.Bids)
+	ask, bid, ok := book.top()
+	s.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	updateTime := time.Now()
+	if event.Timestamp > 0 {
+		updateTime = time.UnixMilli(event.Timestamp)
+	}
+	updater.UpdateQuote(domain.QuoteKey{Exchange: constant.MAX, Pair: pair}, domain.QuotationInfo{
+		BuyPrice:   ask,
+		SellPrice:  bid,
+		UpdateTime: updateTime,
+		Source:     StreamSourceName,
+	})
+}
+
+func (b *streamBook) apply(side map[string]domain.DepthLevel, levels [][2]decimal.Decimal) {
+	for _, v := range levels {
+		if v[1].IsZero() {
+			delete(side, v[0].String())
+			continue
+		}
+		side[v[0].String()] = domain.DepthLevel{Price: v[0], Volume: v[1]}
+	}
+}
+
+func (b *streamBook) top() (ask decimal.Decimal, bid decimal.Decimal, ok bool) {
+	if len(b.asks) == 0 || len(b.bids) == 0 {
+		return decimal.Zero, decimal.Zero, false
+	}
+	first := true
+	for _, v := range b.asks {
+		if first || v.Price.LessThan(ask) {
+			ask, first = v.Price, false
+		}
+	}
+	first = true
+	for _, v := range b.bids {
+		if first || v.Price.GreaterThan(bid) {
+			bid, first = v.Price, false
+		}
+	}
+	return ask, bid, true
+}
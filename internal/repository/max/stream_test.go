@@ -0,0 +1,136 @@
+package max
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/quote_book"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport/wstest"
+	"github.com/shopspring/decimal"
+)
+
+const snapshot = `{"c":"book","e":"snapshot","M":"usdttwd","a":[["32.45","100"],["32.5","200"]],"b":[["32.41","50"],["32.3","80"]],"T":1700000000000}`
+
+// runStream follows usdttwd on a stand-in server answering every subscription with snapshot,
+// heartbeats and backoff are shortened so that reconnects happen within a test
+func runStream(t *testing.T) (*wstest.Server, *quote_book.QuoteBook) {
+	t.Helper()
+	srv := wstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.OnMessage(func(msg []byte) [][]byte {
+		if strings.Contains(string(msg), `"action":"sub"`) {
+			return [][]byte{[]byte(snapshot)}
+		}
+		return nil
+	})
+
+	stream := NewMaxStream(srv.URL(), []constant.Pair{constant.USDTTWD})
+	stream.cfg.PingInterval = 50 * time.Millisecond
+	stream.cfg.HeartbeatTimeout = 200 * time.Millisecond
+	stream.cfg.Backoff.BaseDelay = 10 * time.Millisecond
+	stream.cfg.Backoff.MaxDelay = 50 * time.Millisecond
+
+	book := quote_book.NewQuoteBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stream.Run(ctx, book)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	if !srv.WaitConnects(1, time.Second) {
+		t.Fatal("stream never connected")
+	}
+	return srv, book
+}
+
+// waitQuote waits until the book holds the usdttwd quote of MAX with the given prices
+func waitQuote(t *testing.T, book *quote_book.QuoteBook, buy, sell string) {
+	t.Helper()
+	var info domain.QuotationInfo
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		resp, err := book.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD))
+		if err != nil {
+			continue
+		}
+		info, _ = resp.Quotation(constant.MAX, constant.USDTTWD)
+		if info.BuyPrice.Equal(decimal.RequireFromString(buy)) && info.SellPrice.Equal(decimal.RequireFromString(sell)) {
+			return
+		}
+	}
+	t.Fatalf("want %s/%s in the book, last saw %s/%s", buy, sell, info.BuyPrice, info.SellPrice)
+}
+
+func TestMaxStreamSubscribes(t *testing.T) {
+	srv, book := runStream(t)
+	waitQuote(t, book, "32.45", "32.41")
+
+	received := srv.Received()
+	if len(received) != 1 {
+		t.Fatalf("want one subscription, got %q", received)
+	}
+	for _, want := range []string{`"action":"sub"`, `"channel":"book"`, `"market":"usdttwd"`} {
+		if !strings.Contains(received[0], want) {
+			t.Errorf("subscription %s lacks %s", received[0], want)
+		}
+	}
+}
+
+func TestMaxStreamUpdatesQuoteBook(t *testing.T) {
+	srv, book := runStream(t)
+	waitQuote(t, book, "32.45", "32.41")
+
+	// the best ask is taken and a better bid arrives
+	srv.Broadcast(`{"c":"book","e":"update","M":"usdttwd","a":[["32.45","0"]],"b":[["32.43","10"]],"T":1700000001000}`)
+	waitQuote(t, book, "32.5", "32.43")
+
+	resp, err := book.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := resp.Quotation(constant.MAX, constant.USDTTWD)
+	if info.Source != StreamSourceName || !info.UpdateTime.Equal(time.UnixMilli(1700000001000)) {
+		t.Errorf("unexpected source %q or update time %s", info.Source, info.UpdateTime)
+	}
+
+	// other markets are ignored
+	srv.Broadcast(`{"c":"book","e":"snapshot","M":"btctwd","a":[["1","1"]],"b":[["1","1"]],"T":1700000002000}`)
+	btc := constant.Pair{Base: constant.BTC, Quote: constant.TWD}
+	if _, err := book.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(btc)); err == nil {
+		t.Error("want no quote of an unsubscribed market")
+	}
+}
+
+func TestMaxStreamReconnectsAfterDrop(t *testing.T) {
+	srv, book := runStream(t)
+	waitQuote(t, book, "32.45", "32.41")
+
+	srv.Drop()
+	if !srv.WaitConnects(2, time.Second) {
+		t.Fatal("stream did not reconnect after a drop")
+	}
+	waitQuote(t, book, "32.45", "32.41")
+
+	if n := len(srv.Received()); n != 2 {
+		t.Errorf("want the subscription sent again, got %d messages", n)
+	}
+}
+
+func TestMaxStreamReconnectsOnPingTimeout(t *testing.T) {
+	srv, book := runStream(t)
+	waitQuote(t, book, "32.45", "32.41")
+
+	// without pongs or messages the heartbeat times out
+	srv.Silence(true)
+	if !srv.WaitConnects(2, time.Second) {
+		t.Fatal("stream did not reconnect after missing pongs")
+	}
+}
@@ -0,0 +1,83 @@
+package quote_book
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// QuoteBook keeps the latest streamed quote of every exchange and pair in memory
+type QuoteBook struct {
+	lock   sync.RWMutex
+	quotes map[domain.QuoteKey]domain.QuotationInfo
+	subs   []chan domain.QuoteKey
+}
+
+var (
+	_ domain.QuoteRepo    = (*QuoteBook)(nil)
+	_ domain.QuoteUpdater = (*QuoteBook)(nil)
+)
+
+func NewQuoteBook() *QuoteBook {
+	return &QuoteBook{quotes: make(map[domain.QuoteKey]domain.QuotationInfo)}
+}
+
+// changesBuffer is how many changes a slow subscriber may lag behind, later
+// ones are dropped since the book already holds the newest price
+var changesBuffer = 64
+
+// Subscribe returns a channel receiving the key of every price change
+func (b *QuoteBook) Subscribe() <-chan domain.QuoteKey {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ch := make(chan domain.QuoteKey, changesBuffer)
+	b.subs = append(b.subs, ch)
+	return ch
+}
+
+// UpdateQuote stores info unless a newer quote is already kept, subscribers
+// hear about it only when a price changed
+func (b *QuoteBook) UpdateQuote(key domain.QuoteKey, info domain.QuotationInfo) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	current, ok := b.quotes[key]
+	if ok && info.UpdateTime.Before(current.UpdateTime) {
+		return
+	}
+	b.quotes[key] = info
+
+	if ok && current.BuyPrice.Equal(info.BuyPrice) && current.SellPrice.Equal(info.SellPrice) {
+		return
+	}
+	for _, ch := range b.subs {
+		select {
+		case ch <- key:
+		default:
+		}
+	}
+}
+
+func (b *QuoteBook) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+	pair := req.Pair()
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	infos := make(map[domain.QuoteKey]domain.QuotationInfo)
+	for k, v := range b.quotes {
+		if k.Pair == pair {
+			infos[k] = v
+		}
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("%w: no streamed %s quote yet", domain.ErrQuoteNotStreamed, pair)
+	}
+
+	// the book is live, every read is a fresh snapshot
+	return &domain.GetQuotationsResponse{Infos: infos, FetchedAt: time.Now()}, nil
+}
@@ -0,0 +1,87 @@
+package quote_cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+type quoteCache struct {
+	next domain.QuoteRepo
+	ttl  time.Duration
+
+	lock      sync.Mutex
+	snapshots map[constant.Pair]*domain.GetQuotationsResponse
+	calls     map[constant.Pair]*call
+}
+
+var _ domain.QuoteRepo = (*quoteCache)(nil)
+
+// call is an in-flight upstream request that concurrent callers wait on
+type call struct {
+	done chan struct{}
+	resp *domain.GetQuotationsResponse
+	err  error
+}
+
+// NewQuoteCache serves snapshots of next for ttl and coalesces concurrent
+// misses for the same pair into one upstream call.
+// The returned response is shared between callers and must not be modified.
+func NewQuoteCache(next domain.QuoteRepo, ttl time.Duration) domain.QuoteRepo {
+	return &quoteCache{
+		next:      next,
+		ttl:       ttl,
+		snapshots: make(map[constant.Pair]*domain.GetQuotationsResponse),
+		calls:     make(map[constant.Pair]*call),
+	}
+}
+
+func (q *quoteCache) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+	// an empty request and an explicit USDT/TWD one share a snapshot
+	pair := req.Pair()
+
+	q.lock.Lock()
+
+	if snapshot, ok := q.snapshots[pair]; ok && snapshot.Age() < q.ttl {
+		q.lock.Unlock()
+		return snapshot, nil
+	}
+
+	c, ok := q.calls[pair]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		q.calls[pair] = c
+		// detached from the caller so that one canceled caller does not fail the others
+		go q.fetch(pair, c)
+	}
+	q.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return c.resp, c.err
+	}
+}
+
+func (q *quoteCache) fetch(pair constant.Pair, c *call) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c.resp, c.err = q.next.GetQuotations(ctx, domain.NewGetQuotationsRequest(pair))
+	if c.err == nil && c.resp.FetchedAt.IsZero() {
+		c.resp.FetchedAt = time.Now()
+	}
+
+	q.lock.Lock()
+	if c.err == nil {
+		q.snapshots[pair] = c.resp
+	}
+	delete(q.calls, pair)
+	q.lock.Unlock()
+
+	close(c.done)
+}
@@ -0,0 +1,131 @@
+package quote_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// countingQuotes counts the upstream calls, each one waits for release when it is set
+type countingQuotes struct {
+	calls   atomic.Int32
+	entered chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (c *countingQuotes) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		c.entered <- struct{}{}
+		<-c.release
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &domain.GetQuotationsResponse{Infos: map[domain.QuoteKey]domain.QuotationInfo{}}, nil
+}
+
+func TestQuoteCacheTTL(t *testing.T) {
+	next := &countingQuotes{}
+	cache := NewQuoteCache(next, 50*time.Millisecond)
+	ctx := context.Background()
+
+	first, err := cache.GetQuotations(ctx, domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.FetchedAt.IsZero() {
+		t.Error("want FetchedAt set on the snapshot")
+	}
+
+	// an empty request and an explicit USDT/TWD one share the snapshot
+	second, err := cache.GetQuotations(ctx, domain.NewGetQuotationsRequest(constant.USDTTWD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first || next.calls.Load() != 1 {
+		t.Fatalf("want the cached snapshot within the ttl, got %d upstream calls", next.calls.Load())
+	}
+
+	// another pair has its own snapshot
+	if _, err := cache.GetQuotations(ctx, domain.NewGetQuotationsRequest(constant.Pair{Base: constant.BTC, Quote: constant.TWD})); err != nil {
+		t.Fatal(err)
+	}
+	if n := next.calls.Load(); n != 2 {
+		t.Fatalf("want another pair to go upstream, got %d calls", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	third, err := cache.GetQuotations(ctx, domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first || next.calls.Load() != 3 {
+		t.Fatalf("want a new snapshot after the ttl, got %d upstream calls", next.calls.Load())
+	}
+}
+
+func TestQuoteCacheSkipsErrors(t *testing.T) {
+	next := &countingQuotes{err: errors.New("upstream down")}
+	cache := NewQuoteCache(next, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetQuotations(context.Background(), domain.GetQuotationsRequest{}); err == nil {
+			t.Fatal("want the upstream error")
+		}
+	}
+	if n := next.calls.Load(); n != 2 {
+		t.Fatalf("want a failure retried on the next call, got %d upstream calls", n)
+	}
+}
+
+func TestQuoteCacheCoalescesMisses(t *testing.T) {
+	next := &countingQuotes{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	cache := NewQuoteCache(next, time.Minute)
+
+	// the first caller gives up while the upstream call is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := cache.GetQuotations(ctx, domain.GetQuotationsRequest{})
+		canceled <- err
+	}()
+	<-next.entered
+	cancel()
+	if err := <-canceled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+
+	const callers = 10
+	resps := make([]*domain.GetQuotationsResponse, callers)
+	errs := make([]error, callers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i], errs[i] = cache.GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+		}(i)
+	}
+
+	// give the callers time to join the call in flight before it returns
+	time.Sleep(20 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("want one upstream call, got %d", n)
+	}
+	for i := range resps {
+		if errs[i] != nil || resps[i] != resps[0] {
+			t.Fatalf("want every caller to share the snapshot, got %v, %v", resps[i], errs[i])
+		}
+	}
+}
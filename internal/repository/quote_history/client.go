@@ -0,0 +1,35 @@
+package quote_history
+
+import (
+	"context"
+	"log"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+type quoteHistory struct {
+	next    domain.QuoteRepo
+	history domain.HistoryRepo
+}
+
+var _ domain.QuoteRepo = (*quoteHistory)(nil)
+
+// NewHistoryRecorder records every snapshot served by next into history.
+// A failed write is logged and never fails the quote request.
+func NewHistoryRecorder(next domain.QuoteRepo, history domain.HistoryRepo) domain.QuoteRepo {
+	return &quoteHistory{next: next, history: history}
+}
+
+func (h *quoteHistory) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	resp, err := h.next.GetQuotations(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.history.RecordQuotations(ctx, resp); err != nil {
+		log.Println("record quote history failed: ", req.Pair(), err.Error())
+	}
+
+	return resp, nil
+}
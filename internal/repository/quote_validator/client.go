@@ -0,0 +1,48 @@
+package quote_validator
+
+import (
+	"context"
+	"log"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+type quoteValidator struct {
+	next domain.QuoteRepo
+}
+
+var _ domain.QuoteRepo = (*quoteValidator)(nil)
+
+// NewQuoteValidator moves quotes that fail domain.ValidateQuotation from Infos to Rejected
+func NewQuoteValidator(next domain.QuoteRepo) domain.QuoteRepo {
+	return &quoteValidator{next: next}
+}
+
+func (v *quoteValidator) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	resp, err := v.next.GetQuotations(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	validated := &domain.GetQuotationsResponse{
+		Infos:     make(map[domain.QuoteKey]domain.QuotationInfo, len(resp.Infos)),
+		FetchedAt: resp.FetchedAt,
+		Rejected:  make(map[domain.QuoteKey]error, len(resp.Rejected)),
+	}
+
+	for key, err := range resp.Rejected {
+		validated.Rejected[key] = err
+	}
+
+	for key, info := range resp.Infos {
+		if err := domain.ValidateQuotation(key, info); err != nil {
+			log.Println("reject quotation: ", err.Error())
+			validated.Rejected[key] = err
+			continue
+		}
+		validated.Infos[key] = info
+	}
+
+	return validated, nil
+}
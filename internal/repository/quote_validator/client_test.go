@@ -0,0 +1,81 @@
+package quote_validator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// stubQuotes answers every request with the same response or error
+type stubQuotes struct {
+	resp *domain.GetQuotationsResponse
+	err  error
+}
+
+func (s *stubQuotes) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+	return s.resp, s.err
+}
+
+func TestQuoteValidator(t *testing.T) {
+	quote := func(buy, sell string) domain.QuotationInfo {
+		return domain.QuotationInfo{BuyPrice: decimal.RequireFromString(buy), SellPrice: decimal.RequireFromString(sell), UpdateTime: time.Now()}
+	}
+	key := func(exchange constant.Exchange) domain.QuoteKey {
+		return domain.QuoteKey{Exchange: exchange, Pair: constant.USDTTWD}
+	}
+
+	fetchedAt := time.Now().Add(-time.Second)
+	next := &stubQuotes{resp: &domain.GetQuotationsResponse{
+		Infos: map[domain.QuoteKey]domain.QuotationInfo{
+			key(constant.MAX):     quote("32.45", "32.41"),
+			key(constant.BitoPro): quote("0", "32.41"),
+			key(constant.Rybit):   quote("32.45", "-1"),
+			key(constant.ACE):     quote("32.3", "32.6"),
+		},
+		FetchedAt: fetchedAt,
+		Rejected: map[domain.QuoteKey]error{
+			key(constant.BinanceP2P): &domain.QuoteError{Exchange: constant.BinanceP2P, Pair: constant.USDTTWD, Err: domain.ErrStaleQuote},
+		},
+	}}
+
+	resp, err := NewQuoteValidator(next).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Infos) != 1 || !resp.FetchedAt.Equal(fetchedAt) {
+		t.Fatalf("want only the MAX quote at %s, got %+v", fetchedAt, resp)
+	}
+	if _, ok := resp.Infos[key(constant.MAX)]; !ok {
+		t.Errorf("want the valid MAX quote kept, got %+v", resp.Infos)
+	}
+
+	for exchange, want := range map[constant.Exchange]error{
+		constant.BitoPro:    domain.ErrInvalidPrice,
+		constant.Rybit:      domain.ErrInvalidPrice,
+		constant.ACE:        domain.ErrInvertedQuote,
+		constant.BinanceP2P: domain.ErrStaleQuote,
+	} {
+		err := resp.Rejected[key(exchange)]
+		quoteErr := &domain.QuoteError{}
+		if !errors.Is(err, want) || !errors.As(err, &quoteErr) || quoteErr.Exchange != exchange {
+			t.Errorf("%s: want %v, got %v", exchange, want, err)
+		}
+	}
+
+	// the response of next is left as it was
+	if len(next.resp.Infos) != 4 || len(next.resp.Rejected) != 1 {
+		t.Errorf("want the upstream response untouched, got %+v", next.resp)
+	}
+}
+
+func TestQuoteValidatorPassesErrors(t *testing.T) {
+	_, err := NewQuoteValidator(&stubQuotes{err: domain.ErrPairUnsupported}).GetQuotations(context.Background(), domain.GetQuotationsRequest{})
+	if !errors.Is(err, domain.ErrPairUnsupported) {
+		t.Fatalf("want ErrPairUnsupported, got %v", err)
+	}
+}
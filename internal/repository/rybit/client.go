@@ -0,0 +1,89 @@
+package rybit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/exchange"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+type rybitClient struct {
+	cli      transport.HttpClient
+	endpoint string
+	maxAge   time.Duration
+}
+
+var _ domain.QuoteRepo = (*rybitClient)(nil)
+
+// NewRybitClient reads rybit's own buy and sell rates at endpoint,
+// rates older than maxAge are rejected with domain.ErrStaleQuote
+func NewRybitClient(cli transport.HttpClient, endpoint string, maxAge time.Duration) domain.QuoteRepo {
+	return &rybitClient{
+		cli:      cli,
+		endpoint: endpoint,
+		maxAge:   maxAge,
+	}
+}
+
+func init() {
+	exchange.Register(constant.Rybit, func(cli transport.HttpClient, cfg *config.ExchangeCfg) domain.QuoteRepo {
+		return NewRybitClient(cli, cfg.RybitEndpoint, cfg.StaleQuoteAgeOf(constant.Rybit))
+	})
+}
+
+var (
+	// the rate of a pair is at e.g. /v1/exchange-rates/USDT-TWD
+	pathExchangeRate = "/v1/exchange-rates/%s-%s"
+)
+
+func (c *rybitClient) GetQuotations(ctx context.Context, req domain.GetQuotationsRequest) (*domain.GetQuotationsResponse, error) {
+
+	pair := req.Pair()
+	if pair.Quote != constant.TWD {
+		return nil, fmt.Errorf("%w: rybit only quotes TWD: %s", domain.ErrPairUnsupported, pair)
+	}
+	url := fmt.Sprintf("%s%s", c.endpoint, fmt.Sprintf(pathExchangeRate, pair.Base, pair.Quote))
+	httpResp, err := c.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	respBody := &exchangeRateRespBody{}
+	if err := json.Unmarshal(httpResp.Body, respBody); err != nil {
+		return nil, fmt.Errorf("%w: rybit: %s", domain.ErrMalformedQuote, err.Error())
+	}
+
+	if respBody.Code != 0 {
+		return nil, fmt.Errorf("%w: rybit %d: %s", domain.ErrQuoteUnavailable, respBody.Code, respBody.Message)
+	}
+
+	data := respBody.Data
+	switch {
+	case data == nil:
+		return nil, fmt.Errorf("%w: rybit: missing data", domain.ErrMalformedQuote)
+	case data.BuyRate == nil || !data.BuyRate.IsPositive():
+		return nil, fmt.Errorf("%w: rybit: invalid buy rate", domain.ErrMalformedQuote)
+	case data.SellRate == nil || !data.SellRate.IsPositive():
+		return nil, fmt.Errorf("%w: rybit: invalid sell rate", domain.ErrMalformedQuote)
+	case data.UpdateTime <= 0:
+		return nil, fmt.Errorf("%w: rybit: missing update time", domain.ErrMalformedQuote)
+	}
+
+	info := domain.QuotationInfo{
+		BuyPrice:   *data.BuyRate,
+		SellPrice:  *data.SellRate,
+		UpdateTime: time.UnixMilli(data.UpdateTime),
+	}
+
+	return domain.NewQuoteResponse(domain.QuoteKey{Exchange: constant.Rybit, Pair: pair}, info, c.maxAge), nil
+}
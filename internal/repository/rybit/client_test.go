@@ -0,0 +1,115 @@
+package rybit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+// newStandIn serves GET /v1/exchange-rates/{base}-{quote}, rates is the body of each known path
+func newStandIn(t *testing.T, rates map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	hits := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, ok := rates[r.URL.Path]
+		if !ok {
+			body = `{"code":40001,"message":"symbol not supported","data":null}`
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func rateBody(buy, sell string, updateTime time.Time) string {
+	return fmt.Sprintf(`{"code":0,"message":"success","data":{"symbol":"USDT-TWD","buy_rate":"%s","sell_rate":"%s","update_time":%d}}`,
+		buy, sell, updateTime.UnixMilli())
+}
+
+func TestGetQuotations(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	srv, _ := newStandIn(t, map[string]string{
+		"/v1/exchange-rates/USDT-TWD": rateBody("32.52", "32.31", now),
+	})
+	cli := NewRybitClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := resp.Quotation(constant.Rybit, constant.USDTTWD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.BuyPrice.Equal(decimal.RequireFromString("32.52")) || !info.SellPrice.Equal(decimal.RequireFromString("32.31")) {
+		t.Errorf("unexpected prices %s/%s", info.BuyPrice, info.SellPrice)
+	}
+	if !info.UpdateTime.Equal(now) {
+		t.Errorf("unexpected update time %s", info.UpdateTime)
+	}
+}
+
+func TestGetQuotationsNonTWDPair(t *testing.T) {
+	srv, hits := newStandIn(t, nil)
+	cli := NewRybitClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	pair := constant.Pair{Base: constant.USDC, Quote: constant.USDT}
+	_, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(pair))
+	if !errors.Is(err, domain.ErrPairUnsupported) {
+		t.Errorf("want %v, got %v", domain.ErrPairUnsupported, err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("want no request for an unsupported pair, got %d", n)
+	}
+}
+
+func TestGetQuotationsErrors(t *testing.T) {
+	srv, _ := newStandIn(t, map[string]string{
+		"/v1/exchange-rates/USDT-TWD": rateBody("32.52", "32.31", time.Now().Add(-10*time.Minute)),
+		"/v1/exchange-rates/USDC-TWD": rateBody("0", "32.31", time.Now()),
+		"/v1/exchange-rates/BTC-TWD":  `{"code":0,"message":"success","data":null}`,
+	})
+	cli := NewRybitClient(transport.NewHttpClient(), srv.URL, time.Minute)
+
+	for _, c := range []struct {
+		pair constant.Pair
+		want error
+	}{
+		{constant.USDTTWD, domain.ErrStaleQuote},
+		{constant.Pair{Base: constant.USDC, Quote: constant.TWD}, domain.ErrMalformedQuote},
+		{constant.Pair{Base: constant.BTC, Quote: constant.TWD}, domain.ErrMalformedQuote},
+		{constant.Pair{Base: constant.ETH, Quote: constant.TWD}, domain.ErrQuoteUnavailable},
+	} {
+		// a stale quote is rejected in the response, the rest fail the call
+		resp, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(c.pair))
+		if err == nil {
+			_, err = resp.Quotation(constant.Rybit, c.pair)
+		}
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: want %v, got %v", c.pair, c.want, err)
+		}
+	}
+}
+
+func TestGetQuotationsNoMaxAge(t *testing.T) {
+	srv, _ := newStandIn(t, map[string]string{
+		"/v1/exchange-rates/USDT-TWD": rateBody("32.52", "32.31", time.Now().Add(-24*time.Hour)),
+	})
+	// a zero max age disables the staleness check
+	cli := NewRybitClient(transport.NewHttpClient(), srv.URL, 0)
+
+	if _, err := cli.GetQuotations(context.Background(), domain.NewGetQuotationsRequest(constant.USDTTWD)); err != nil {
+		t.Errorf("want the old rate accepted, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package rybit
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type exchangeRateRespBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *struct {
+		Symbol     string           `json:"symbol"`
+		BuyRate    *decimal.Decimal `json:"buy_rate"`
+		SellRate   *decimal.Decimal `json:"sell_rate"`
+		UpdateTime int64            `json:"update_time"`
+	} `json:"data"`
+}
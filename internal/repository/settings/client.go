@@ -0,0 +1,146 @@
+// Package settings keeps the per chat settings in an embedded bbolt file, one
+// JSON value per chat keyed by its chat id.
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketChats = []byte("chats")
+
+type boltSettings struct {
+	db *bolt.DB
+}
+
+var _ domain.SettingsRepo = (*boltSettings)(nil)
+
+// NewBoltSettings opens or creates the store at path
+func NewBoltSettings(path string) (domain.SettingsRepo, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open settings %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketChats)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltSettings{db: db}, nil
+}
+
+// Close releases the file lock, the store must not be used afterwards
+func (s *boltSettings) Close() error {
+	return s.db.Close()
+}
+
+// storedSettings is the value of a chat, its id is the key
+type storedSettings struct {
+	Values     map[string]decimal.Decimal `json:"v"`
+	Subscribed bool                       `json:"sub,omitempty"`
+	UpdatedBy  int64                      `json:"by"`
+	UpdatedAt  int64                      `json:"at"`
+}
+
+func chatKey(chatID int64) []byte {
+	return []byte(strconv.FormatInt(chatID, 10))
+}
+
+func (s *boltSettings) GetChatSettings(ctx context.Context, chatID int64) (*domain.ChatSettings, error) {
+	settings := &domain.ChatSettings{ChatID: chatID, Values: make(map[domain.SettingKey]decimal.Decimal)}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketChats).Get(chatKey(chatID))
+		if value == nil {
+			return nil
+		}
+		return decodeSettings(value, settings)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *boltSettings) ListChatSettings(ctx context.Context) ([]*domain.ChatSettings, error) {
+	list := []*domain.ChatSettings{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketChats).ForEach(func(k, v []byte) error {
+			chatID, err := strconv.ParseInt(string(k), 10, 64)
+			if err != nil {
+				return fmt.Errorf("decode chat id %q: %w", k, err)
+			}
+			settings := &domain.ChatSettings{ChatID: chatID, Values: make(map[domain.SettingKey]decimal.Decimal)}
+			if err := decodeSettings(v, settings); err != nil {
+				return err
+			}
+			list = append(list, settings)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// decodeSettings fills settings with the stored value of its chat
+func decodeSettings(value []byte, settings *domain.ChatSettings) error {
+	stored := storedSettings{}
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return fmt.Errorf("decode settings of chat %d: %w", settings.ChatID, err)
+	}
+	// keys dropped since they were stored are ignored
+	for k, v := range stored.Values {
+		if key, ok := domain.LookupSettingKey(k); ok {
+			settings.Values[key] = v
+		}
+	}
+	settings.Subscribed = stored.Subscribed
+	settings.UpdatedBy = stored.UpdatedBy
+	if stored.UpdatedAt > 0 {
+		settings.UpdatedAt = time.Unix(0, stored.UpdatedAt)
+	}
+	return nil
+}
+
+// SaveChatSettings deletes the settings of a chat once they are back to the defaults
+func (s *boltSettings) SaveChatSettings(ctx context.Context, settings *domain.ChatSettings) error {
+	if settings.IsEmpty() {
+		return s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(bucketChats).Delete(chatKey(settings.ChatID))
+		})
+	}
+
+	stored := storedSettings{
+		Values:     make(map[string]decimal.Decimal, len(settings.Values)),
+		Subscribed: settings.Subscribed,
+		UpdatedBy:  settings.UpdatedBy,
+		UpdatedAt:  settings.UpdatedAt.UnixNano(),
+	}
+	for k, v := range settings.Values {
+		stored.Values[string(k)] = v
+	}
+
+	value, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketChats).Put(chatKey(settings.ChatID), value)
+	})
+}
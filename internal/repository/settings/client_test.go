@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+func TestListChatSettings(t *testing.T) {
+	repo, err := NewBoltSettings(filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.(*boltSettings).Close()
+
+	ctx := context.Background()
+	list, err := repo.ListChatSettings(ctx)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("want no chats in a new store, got %v, %v", list, err)
+	}
+
+	for _, chatID := range []int64{-781207517, 6040823283} {
+		err := repo.SaveChatSettings(ctx, &domain.ChatSettings{
+			ChatID:    chatID,
+			Values:    map[domain.SettingKey]decimal.Decimal{domain.SettingMinArbitrage: decimal.RequireFromString("0.008")},
+			UpdatedBy: 42,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err = repo.ListChatSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ChatID < list[j].ChatID })
+	if len(list) != 2 || list[0].ChatID != -781207517 || list[1].ChatID != 6040823283 {
+		t.Fatalf("unexpected chats %+v", list)
+	}
+	if v := list[0].Values[domain.SettingMinArbitrage]; !v.Equal(decimal.RequireFromString("0.008")) || list[0].UpdatedBy != 42 {
+		t.Errorf("unexpected settings %+v", list[0])
+	}
+}
+
+func TestSaveChatSettingsDropsDefaults(t *testing.T) {
+	repo, err := NewBoltSettings(filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.(*boltSettings).Close()
+
+	ctx := context.Background()
+	if err := repo.SaveChatSettings(ctx, &domain.ChatSettings{ChatID: 6040823283, Subscribed: true}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := repo.ListChatSettings(ctx)
+	if err != nil || len(list) != 1 || !list[0].Subscribed {
+		t.Fatalf("want a subscribed chat, got %+v, %v", list, err)
+	}
+
+	// unsubscribing with no values left brings the chat back to the defaults
+	if err := repo.SaveChatSettings(ctx, &domain.ChatSettings{ChatID: 6040823283}); err != nil {
+		t.Fatal(err)
+	}
+	list, err = repo.ListChatSettings(ctx)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("want the record deleted, got %+v, %v", list, err)
+	}
+}
@@ -1,12 +1,16 @@
 package telegram_bot
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/config"
@@ -27,23 +31,45 @@ var _ domain.TelegramBotRepo = (*telegramBotRepo)(nil)
 func NewTelegramBotRepo(cli transport.HttpClient, cfg *config.TelegramCfg) domain.TelegramBotRepo {
 	return &telegramBotRepo{
 		cli:      cli,
-		endpoint: fmt.Sprintf("https://api.telegram.org/bot%s", cfg.QuoteComparisonBot.Token),
+		endpoint: fmt.Sprintf("%s/bot%s", cfg.APIEndpoint, cfg.QuoteComparisonBot.Token),
 		cfg:      cfg,
 	}
 }
 
 var (
-	pathSendMessage = "/sendMessage"
-	pathGetUpdates  = "/getUpdates"
+	pathSendMessage   = "/sendMessage"
+	pathSendPhoto     = "/sendPhoto"
+	pathGetUpdates    = "/getUpdates"
+	pathGetChatMember = "/getChatMember"
 )
 
 func (t *telegramBotRepo) SendArbitrageNotify(ctx context.Context, req domain.SendArbitrageNotifyRequest) error {
+	if len(req.Hops) > 0 {
+		return t.sendCycleNotify(ctx, req)
+	}
 
 	arbitrage := req.Arbitrage.Mul(decimal.New(1, 2)).Truncate(2).String() + "%"
+	effective := fmt.Sprintf("%s%% (slippage %s bps)",
+		req.EffectiveArbitrage.Mul(decimal.New(1, 2)).Truncate(2),
+		req.SlippageBps.Truncate(1),
+	)
+	net := fmt.Sprintf("%s%% (profit %s %s)",
+		req.NetArbitrage.Mul(decimal.New(1, 2)).Truncate(2),
+		req.NetProfit.Truncate(0),
+		req.Pair.Quote,
+	)
 	if req.IsExcitedArbitrage {
-		arbitrage = fmt.Sprintf("%s%s%s", constant.EmojiCelebration, arbitrage, constant.EmojiCelebration)
+		net = fmt.Sprintf("%s%s%s", constant.EmojiCelebration, net, constant.EmojiCelebration)
+	}
+
+	maxSize := "unlimited"
+	if req.MaxInvestAmount != nil {
+		maxSize = fmt.Sprintf("%s %s", req.MaxInvestAmount.Truncate(0), req.Pair.Quote)
 	}
 	spread := req.Spread.String()
+	if !req.NetSpread.Equal(req.Spread) {
+		spread = fmt.Sprintf("%s (net %s)", spread, req.NetSpread.Truncate(4))
+	}
 	if req.IsExcitedSpread {
 		spread = fmt.Sprintf("%s%s%s", constant.EmojiCelebration, spread, constant.EmojiCelebration)
 	}
@@ -51,12 +77,61 @@ func (t *telegramBotRepo) SendArbitrageNotify(ctx context.Context, req domain.Se
 	// profit := req.Profit.Truncate(0).String()
 	tmpl := tmplArbitrageNotifySimple
 	text := tmpl.Format(
+		req.Pair,
 		spread,
 		req.ExchangeBuy,
 		req.BuyPrice,
 		req.ExchangeSell,
 		req.SellPrice,
 		arbitrage,
+		effective,
+		net,
+		fmt.Sprintf("%s %s, %s %s", req.ExchangeBuy, legFees(req.BuyFees), req.ExchangeSell, legFees(req.SellFees)),
+		fmt.Sprintf("Buy %s, Sell %s for %s %s",
+			req.VWAPBuyPrice.Round(4), req.VWAPSellPrice.Round(4), req.InvestAmount.Truncate(0), req.Pair.Quote,
+		),
+		maxSize,
+		fmt.Sprintf("%s %s, %s %s, fetched %s ago",
+			req.ExchangeBuy, req.BuyQuoteAge.Truncate(time.Second),
+			req.ExchangeSell, req.SellQuoteAge.Truncate(time.Second),
+			req.QuoteAge.Truncate(time.Second),
+		),
+		t.cfg.AuthorID,
+		t.cfg.Author,
+	)
+
+	return t.SendMessage(ctx, domain.SendMessageRequest{
+		ChatID:    req.ChatID,
+		Text:      text,
+		ParseMode: tmpl.Type().String(),
+	})
+}
+
+// sendCycleNotify is the card of a multi hop cycle, one table row per trade
+func (t *telegramBotRepo) sendCycleNotify(ctx context.Context, req domain.SendArbitrageNotifyRequest) error {
+	quote := req.Hops[0].AssetIn
+
+	net := fmt.Sprintf("%s%% (profit %s %s)",
+		req.NetArbitrage.Mul(decimal.New(1, 2)).Truncate(2),
+		req.NetProfit.Truncate(0),
+		quote,
+	)
+	if req.IsExcitedArbitrage {
+		net = fmt.Sprintf("%s%s%s", constant.EmojiCelebration, net, constant.EmojiCelebration)
+	}
+
+	tmpl := tmplCycleNotify
+	text := tmpl.Format(
+		html.EscapeString(cyclePath(req.Hops)),
+		net,
+		fmt.Sprintf("%s %s", req.InvestAmount.Truncate(0), quote),
+		html.EscapeString(hopTable(req.Hops)),
+		fmt.Sprintf("%s %s, %s %s", req.ExchangeBuy, legFees(req.BuyFees), req.ExchangeSell, legFees(req.SellFees)),
+		fmt.Sprintf("%s %s, %s %s, fetched %s ago",
+			req.ExchangeBuy, req.BuyQuoteAge.Truncate(time.Second),
+			req.ExchangeSell, req.SellQuoteAge.Truncate(time.Second),
+			req.QuoteAge.Truncate(time.Second),
+		),
 		t.cfg.AuthorID,
 		t.cfg.Author,
 	)
@@ -68,12 +143,109 @@ func (t *telegramBotRepo) SendArbitrageNotify(ctx context.Context, req domain.Se
 	})
 }
 
+// cyclePath names the assets held along the cycle per exchange, e.g. "MAX TWD>USDT>USDC, BitoPro USDC>TWD"
+func cyclePath(hops []domain.ArbitrageHop) string {
+	parts := []string{}
+	for i, v := range hops {
+		if i == 0 || v.Exchange != hops[i-1].Exchange {
+			parts = append(parts, fmt.Sprintf("%s %s", v.Exchange, v.AssetIn))
+		}
+		parts[len(parts)-1] += ">" + string(v.AssetOut)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func hopTable(hops []domain.ArbitrageHop) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\tTrade\tPrice\tIn\tOut\tFee\t")
+	for i, v := range hops {
+		side := "Sell"
+		if v.Buy {
+			side = "Buy"
+		}
+		fee := fmt.Sprintf("%s %s", hopAmount(v.TradingFee, v.AssetOut), v.AssetOut)
+		if !v.WithdrawalFee.IsZero() {
+			fee = fmt.Sprintf("%s + withdraw %s %s", fee, hopAmount(v.WithdrawalFee, v.AssetIn), v.AssetIn)
+		}
+		fmt.Fprintf(w, "%d\t%s %s %s\t%s\t%s %s\t%s %s\t%s\t\n",
+			i+1, v.Exchange, side, v.Pair, v.Price,
+			hopAmount(v.AmountIn, v.AssetIn), v.AssetIn, hopAmount(v.AmountOut, v.AssetOut), v.AssetOut, fee)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// hopAmount rounds TWD to the dollar and crypto to what exchanges settle
+func hopAmount(amount decimal.Decimal, asset constant.Symbol) string {
+	if asset == constant.TWD {
+		return amount.Round(0).String()
+	}
+	return amount.Round(6).String()
+}
+
+func (t *telegramBotRepo) SendArbitrageRankNotify(ctx context.Context, req domain.SendArbitrageRankNotifyRequest) error {
+
+	tmpl := tmplArbitrageRankNotify
+	text := tmpl.Format(
+		req.Pair,
+		html.EscapeString(rankTable(req)),
+		time.Now().Format("2006-01-02 15:04:05"),
+	)
+
+	return t.SendMessage(ctx, domain.SendMessageRequest{
+		ChatID:    req.ChatID,
+		Text:      text,
+		ParseMode: tmpl.Type().String(),
+	})
+}
+
+// rankTable lists one route per row, net arbitrage and profit are after fees
+func rankTable(req domain.SendArbitrageRankNotifyRequest) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\tRoute\tBuy\tSell\tNet%\tProfit\tMax Size\t")
+	for i, v := range req.Routes {
+		maxSize := "unlimited"
+		if v.MaxInvestAmount != nil {
+			maxSize = v.MaxInvestAmount.Truncate(0).String()
+		}
+		fmt.Fprintf(w, "%d\t%s>%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			i+1, v.ExchangeBuy, v.ExchangeSell, v.BuyPrice, v.SellPrice,
+			v.NetArbitrage.Mul(decimal.New(1, 2)).Truncate(2).StringFixed(2), v.NetProfit.Truncate(0).String(), maxSize)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// legFees lists the non zero fees of a leg, e.g. "trade 750, withdraw 31"
+func legFees(fees domain.LegFees) string {
+	parts := []string{}
+	for _, v := range []struct {
+		name string
+		fee  decimal.Decimal
+	}{
+		{"trade", fees.Trading},
+		{"withdraw", fees.Withdrawal},
+		{"bank", fees.Bank},
+	} {
+		if !v.fee.IsZero() {
+			parts = append(parts, fmt.Sprintf("%s %s", v.name, v.fee.Round(0)))
+		}
+	}
+	if len(parts) == 0 {
+		return "free"
+	}
+	return strings.Join(parts, ", ")
+}
+
 func (t *telegramBotRepo) SendErrorNotify(ctx context.Context, req domain.SendErrorNotifyRequest) error {
 
+	// error texts carry urls, response bodies and the like, which must not be parsed as html
 	tmpl := tmplErrorNotify
 	text := tmpl.Format(
-		req.Title,
-		req.ErrMsg,
+		html.EscapeString(req.Title),
+		html.EscapeString(req.ErrMsg),
 		time.Now().Format("2006-01-02 15:04:05"),
 	)
 
@@ -84,6 +256,170 @@ func (t *telegramBotRepo) SendErrorNotify(ctx context.Context, req domain.SendEr
 	})
 }
 
+func (t *telegramBotRepo) SendDepthNotify(ctx context.Context, req domain.SendDepthNotifyRequest) error {
+
+	tmpl := tmplDepthNotify
+	text := tmpl.Format(
+		req.Depth.Exchange,
+		req.Depth.Pair,
+		depthTable(req.Depth),
+		req.Depth.UpdateTime.Format("2006-01-02 15:04:05"),
+	)
+
+	return t.SendMessage(ctx, domain.SendMessageRequest{
+		ChatID:    req.ChatID,
+		Text:      text,
+		ParseMode: tmpl.Type().String(),
+	})
+}
+
+// depthTable renders asks above bids so that the best prices meet in the middle,
+// cumulative volume and quote notional grow from the top of book outwards
+func depthTable(depth *domain.GetDepthResponse) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "\tPrice\tVol\tCum Vol\tCum %s\t\n", depth.Pair.Quote)
+
+	rows := func(side string, levels []domain.DepthLevel) []string {
+		lines := make([]string, 0, len(levels))
+		cumVolume, cumNotional := decimal.Zero, decimal.Zero
+		for i, v := range levels {
+			cumVolume = cumVolume.Add(v.Volume)
+			cumNotional = cumNotional.Add(v.Price.Mul(v.Volume))
+			lines = append(lines, fmt.Sprintf("%s%d\t%s\t%s\t%s\t%s\t",
+				side, i+1, v.Price, v.Volume.StringFixed(4), cumVolume.StringFixed(4), cumNotional.StringFixed(0)))
+		}
+		return lines
+	}
+
+	asks := rows("A", depth.Asks)
+	for i := len(asks) - 1; i >= 0; i-- {
+		fmt.Fprintln(w, asks[i])
+	}
+	fmt.Fprintln(w, "-\t-\t-\t-\t-\t")
+	for _, v := range rows("B", depth.Bids) {
+		fmt.Fprintln(w, v)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+func (t *telegramBotRepo) SendStatsNotify(ctx context.Context, req domain.SendStatsNotifyRequest) error {
+
+	tmpl := tmplStatsNotify
+	text := tmpl.Format(
+		formatWindow(req.Window),
+		html.EscapeString(statsTable(req)),
+		req.TopN,
+		req.MinArbitrage.Mul(decimal.New(1, 2)).String()+"%",
+		time.Now().Format("2006-01-02 15:04:05"),
+	)
+
+	return t.SendMessage(ctx, domain.SendMessageRequest{
+		ChatID:    req.ChatID,
+		Text:      text,
+		ParseMode: tmpl.Type().String(),
+	})
+}
+
+func (t *telegramBotRepo) SendSettingsNotify(ctx context.Context, req domain.SendSettingsNotifyRequest) error {
+
+	tmpl := tmplSettingsNotify
+	text := tmpl.Format(
+		html.EscapeString(settingsTable(req.Settings)),
+		alertsState(req.Subscribed),
+		time.Now().Format("2006-01-02 15:04:05"),
+	)
+
+	return t.SendMessage(ctx, domain.SendMessageRequest{
+		ChatID:    req.ChatID,
+		Text:      text,
+		ParseMode: tmpl.Type().String(),
+	})
+}
+
+// alertsState tells how a chat turns its alerts on or off
+func alertsState(subscribed bool) string {
+	if subscribed {
+		return "on"
+	}
+	return "off, /subscribe to get them"
+}
+
+// settingsTable lists the values with the ratios in percent and marks the ones the chat set
+func settingsTable(settings []domain.ChatSetting) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 1, ' ', 0)
+	for _, v := range settings {
+		value := v.Value.String()
+		if v.Key.IsRatio() {
+			value = v.Value.Mul(decimal.New(1, 2)).String() + "%"
+		}
+		from := "chat"
+		if v.IsDefault {
+			from = "default"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, value, from)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// statsTable renders one block per route, spread in quote currency and arbitrage in percent
+func statsTable(req domain.SendStatsNotifyRequest) string {
+	buf := &bytes.Buffer{}
+	for i, v := range req.Routes {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "%s %s>%s, %d samples\n", v.Pair, v.ExchangeBuy, v.ExchangeSell, v.Samples)
+		if v.Samples == 0 {
+			continue
+		}
+
+		w := tabwriter.NewWriter(buf, 0, 0, 1, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "\tMin\tMax\tMean\tMed\tP95\t")
+		row := func(name string, s domain.SummaryStats, scale int32, places int32) {
+			fmt.Fprintf(w, "%s\t", name)
+			for _, d := range []decimal.Decimal{s.Min, s.Max, s.Mean, s.Median, s.P95} {
+				fmt.Fprintf(w, "%s\t", d.Shift(scale).StringFixed(places))
+			}
+			fmt.Fprintln(w)
+		}
+		places := int32(4)
+		if v.Spread.Max.Abs().GreaterThanOrEqual(decimal.NewFromInt(100)) {
+			places = 0
+		}
+		row("Spread", v.Spread, 0, places)
+		row("Arb%", v.Arbitrage, 2, 3)
+		w.Flush()
+
+		fmt.Fprintf(buf, "Above min: %d times, %s\n", v.Opportunities, formatWindow(v.OpportunityTime))
+	}
+	return buf.String()
+}
+
+// formatWindow drops the zero units of a duration, e.g. 7d, 24h or 1h30m
+func formatWindow(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	if d == 0 {
+		return "0m"
+	}
+
+	parts := []string{}
+	if days := d / (24 * time.Hour); days > 1 && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", days)
+	}
+	if hours := d / time.Hour; hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes := d % time.Hour / time.Minute; minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(parts, "")
+}
+
 func (t *telegramBotRepo) SendMessage(ctx context.Context, req domain.SendMessageRequest) error {
 
 	url := fmt.Sprintf("%s%s", t.endpoint, pathSendMessage)
@@ -102,7 +438,7 @@ func (t *telegramBotRepo) SendMessage(ctx context.Context, req domain.SendMessag
 		return err
 	}
 
-	_, err = t.cli.Send(ctx, &transport.HttpRequest{
+	httpResp, err := t.cli.Send(ctx, &transport.HttpRequest{
 		Method: http.MethodPost,
 		URL:    url,
 		Body:   data,
@@ -111,10 +447,59 @@ func (t *telegramBotRepo) SendMessage(ctx context.Context, req domain.SendMessag
 		},
 	})
 	if err != nil {
-		log.Println("send message failed", err.Error())
+		return decodeAPIError(httpResp, err)
+	}
+
+	return nil
+}
+
+// SendPhoto uploads the photo as multipart form data, chat_id goes into the query
+// so that the rate limiter still finds the chat
+func (t *telegramBotRepo) SendPhoto(ctx context.Context, req domain.SendPhotoRequest) error {
+
+	url := fmt.Sprintf("%s%s", t.endpoint, pathSendPhoto)
+
+	filename := req.Filename
+	if len(filename) == 0 {
+		filename = "photo.png"
+	}
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	fields := map[string]string{"caption": req.Caption, "parse_mode": req.ParseMode}
+	for k, v := range fields {
+		if len(v) == 0 {
+			continue
+		}
+		if err := form.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("photo", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(req.Photo); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
 		return err
 	}
 
+	httpResp, err := t.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Params: map[string]string{"chat_id": fmt.Sprintf("%d", req.ChatID)},
+		Body:   body.Bytes(),
+		Headers: map[string]string{
+			"Content-Type": form.FormDataContentType(),
+		},
+	})
+	if err != nil {
+		return decodeAPIError(httpResp, err)
+	}
+
 	return nil
 }
 
@@ -132,8 +517,7 @@ func (t *telegramBotRepo) GetUpdates(ctx context.Context, req domain.GetUpdatesR
 		Params: params,
 	})
 	if err != nil {
-		log.Println("get updates failed", err.Error())
-		return nil, err
+		return nil, decodeAPIError(httpResp, err)
 	}
 
 	resp := &updateMessageResp{}
@@ -144,6 +528,9 @@ func (t *telegramBotRepo) GetUpdates(ctx context.Context, req domain.GetUpdatesR
 
 	if !resp.Ok {
 		log.Println("get updates response nok failed")
+		if err := decodeAPIError(httpResp, nil); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("get updates failed: not ok")
 	}
 
@@ -152,6 +539,40 @@ func (t *telegramBotRepo) GetUpdates(ctx context.Context, req domain.GetUpdatesR
 	}, nil
 }
 
+func (t *telegramBotRepo) GetChatMember(ctx context.Context, req domain.GetChatMemberRequest) (*domain.GetChatMemberResponse, error) {
+	url := fmt.Sprintf("%s%s", t.endpoint, pathGetChatMember)
+
+	httpResp, err := t.cli.Send(ctx, &transport.HttpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+		Params: map[string]string{
+			"chat_id": fmt.Sprintf("%d", req.ChatID),
+			"user_id": fmt.Sprintf("%d", req.UserID),
+		},
+	})
+	if err != nil {
+		return nil, decodeAPIError(httpResp, err)
+	}
+
+	resp := &chatMemberResp{}
+	if err := json.Unmarshal(httpResp.Body, resp); err != nil {
+		log.Println("json unmarshal failed", err.Error())
+		return nil, err
+	}
+
+	if !resp.Ok {
+		log.Println("get chat member response nok failed")
+		if err := decodeAPIError(httpResp, nil); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get chat member failed: not ok")
+	}
+
+	return &domain.GetChatMemberResponse{
+		Status: domain.ChatMemberStatus(resp.Result.Status),
+	}, nil
+}
+
 func (t *telegramBotRepo) GetBotCommandUpdates(ctx context.Context, req domain.GetBotCommandUpdatesRequest) (*domain.GetBotCommandUpdatesResponse, error) {
 
 	getUpdatesResp, err := t.GetUpdates(ctx, domain.GetUpdatesRequest{Offset: req.Offset})
@@ -182,7 +603,12 @@ func (t *telegramBotRepo) GetBotCommandUpdates(ctx context.Context, req domain.G
 			continue
 		}
 
-		cmd := constant.CommandType(strings.TrimSuffix(strings.TrimPrefix(*v.Message.Text, "/"), t.cfg.QuoteComparisonBot.Name))
+		fields := strings.Fields(*v.Message.Text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		cmd := constant.CommandType(strings.TrimSuffix(strings.TrimPrefix(fields[0], "/"), t.cfg.QuoteComparisonBot.Name))
 
 		if len(v.Message.Entities) == 0 {
 			continue
@@ -206,6 +632,7 @@ func (t *telegramBotRepo) GetBotCommandUpdates(ctx context.Context, req domain.G
 			FromChatID: v.Message.Chat.ID,
 			FromID:     v.Message.From.ID,
 			Command:    cmd,
+			Args:       fields[1:],
 			Date:       v.Message.Date,
 		})
 	}
@@ -0,0 +1,87 @@
+package telegram_bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+const testToken = "123456:TEST-token_abc"
+
+func newTestConfig(t *testing.T) *config.TelegramCfg {
+	t.Helper()
+	t.Setenv("TELEGRAM_BOT_TOKEN", testToken)
+	return config.NewConfig(true).Telegram
+}
+
+func newReplayRepo(t *testing.T) domain.TelegramBotRepo {
+	t.Helper()
+	cli, err := transport.NewRecorderClient("testdata/bot_api.json", transport.RecorderReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTelegramBotRepo(cli, newTestConfig(t))
+}
+
+func TestGetBotCommandUpdates(t *testing.T) {
+	resp, err := newReplayRepo(t).GetBotCommandUpdates(context.Background(), domain.GetBotCommandUpdatesRequest{Offset: 926617500})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.LastUpdateID == nil || *resp.LastUpdateID != 926617503 {
+		t.Fatalf("want last update id 926617503, got %v", resp.LastUpdateID)
+	}
+	// the plain text message is not a command
+	if len(resp.Infos) != 2 {
+		t.Fatalf("want 2 commands, got %d", len(resp.Infos))
+	}
+
+	arbitrage := resp.Infos[0]
+	if arbitrage.Command != constant.Arbitrage || arbitrage.FromChatID != -781207517 || arbitrage.FromID != 6040823283 {
+		t.Errorf("unexpected command %+v", arbitrage)
+	}
+	if len(arbitrage.Args) != 2 || arbitrage.Args[0] != "usdc" || arbitrage.Args[1] != "5" {
+		t.Errorf("unexpected args %v", arbitrage.Args)
+	}
+	if stats := resp.Infos[1]; stats.Command != constant.Stats || stats.FromChatID != 1881712391 {
+		t.Errorf("unexpected command %+v", stats)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	repo := newReplayRepo(t)
+
+	err := repo.SendMessage(context.Background(), domain.SendMessageRequest{ChatID: -781207517, Text: "<b>hi</b>", ParseMode: HTML.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.SendMessage(context.Background(), domain.SendMessageRequest{ChatID: -905284654, Text: "hi"})
+	if !errors.Is(err, ErrBotBlocked) {
+		t.Fatalf("want ErrBotBlocked, got %v", err)
+	}
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != 403 {
+		t.Fatalf("want a 403 APIError, got %v", err)
+	}
+}
+
+func TestGetChatMember(t *testing.T) {
+	repo := newReplayRepo(t)
+
+	for userID, admin := range map[int64]bool{6040823283: true, 42: false} {
+		resp, err := repo.GetChatMember(context.Background(), domain.GetChatMemberRequest{ChatID: -781207517, UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsAdmin() != admin {
+			t.Errorf("user %d: want admin %v, got status %s", userID, admin, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package telegram_bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+var (
+	ErrBadRequest      = errors.New("telegram: bad request")
+	ErrUnauthorized    = errors.New("telegram: unauthorized")
+	ErrBotBlocked      = errors.New("telegram: bot was blocked or kicked")
+	ErrChatNotFound    = errors.New("telegram: chat not found")
+	ErrChatMigrated    = errors.New("telegram: group chat was migrated to a supergroup")
+	ErrTooManyRequests = errors.New("telegram: too many requests")
+)
+
+// APIError is a decoded {ok:false,error_code,description,parameters} response,
+// it matches one of the sentinel errors above with errors.Is
+type APIError struct {
+	ErrorCode       int
+	Description     string
+	RetryAfter      time.Duration
+	MigrateToChatID int64
+
+	kind  error
+	cause error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram api error %d: %s", e.ErrorCode, e.Description)
+}
+
+func (e *APIError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// Unwrap returns the transport error, e.g. *transport.HTTPStatusError
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+type errorResp struct {
+	Ok          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  *struct {
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+		RetryAfter      int64 `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// decodeAPIError turns a failed bot api call into an *APIError, errors that
+// did not come with a bot api body are returned as they are
+func decodeAPIError(httpResp *transport.HttpResponse, err error) error {
+	if httpResp == nil || len(httpResp.Body) == 0 {
+		return err
+	}
+
+	resp := &errorResp{}
+	if jsonErr := json.Unmarshal(httpResp.Body, resp); jsonErr != nil || resp.Ok {
+		return err
+	}
+
+	apiErr := &APIError{
+		ErrorCode:   resp.ErrorCode,
+		Description: resp.Description,
+		cause:       err,
+	}
+	if resp.Parameters != nil {
+		apiErr.RetryAfter = time.Duration(resp.Parameters.RetryAfter) * time.Second
+		apiErr.MigrateToChatID = resp.Parameters.MigrateToChatID
+	}
+
+	description := strings.ToLower(resp.Description)
+
+	switch {
+	case apiErr.MigrateToChatID != 0 || strings.Contains(description, "upgraded to a supergroup"):
+		apiErr.kind = ErrChatMigrated
+	case resp.ErrorCode == http.StatusTooManyRequests:
+		apiErr.kind = ErrTooManyRequests
+	case resp.ErrorCode == http.StatusUnauthorized:
+		apiErr.kind = ErrUnauthorized
+	case resp.ErrorCode == http.StatusForbidden:
+		apiErr.kind = ErrBotBlocked
+	case strings.Contains(description, "chat not found"):
+		apiErr.kind = ErrChatNotFound
+	case resp.ErrorCode == http.StatusBadRequest:
+		apiErr.kind = ErrBadRequest
+	}
+
+	return apiErr
+}
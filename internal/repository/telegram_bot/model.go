@@ -1,5 +1,12 @@
 package telegram_bot
 
+type chatMemberResp struct {
+	Ok     bool `json:"ok"`
+	Result struct {
+		Status string `json:"status"`
+	} `json:"result"`
+}
+
 type updateMessageResp struct {
 	Ok     bool `json:"ok"`
 	Result []struct {
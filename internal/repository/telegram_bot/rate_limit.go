@@ -0,0 +1,57 @@
+package telegram_bot
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+// RateLimitRule follows the bot api limits: about 30 messages per second in
+// total and 20 messages per minute to the same group, private chats only
+// count towards the total
+func RateLimitRule(cfg *config.TelegramCfg) transport.RateLimitRule {
+	host := "api.telegram.org"
+	if u, err := url.Parse(cfg.APIEndpoint); err == nil && len(u.Hostname()) > 0 {
+		host = u.Hostname()
+	}
+
+	return transport.RateLimitRule{
+		Host:    host,
+		Global:  transport.RateLimit{Rate: 30, Burst: 30},
+		PerKey:  transport.RateLimit{Rate: 20.0 / 60, Burst: 5},
+		KeyFunc: groupChatKey,
+	}
+}
+
+// groupChatKey is the chat of a request to a group or channel, whose ids are
+// negative or @usernames, and empty for a private chat
+func groupChatKey(request *transport.HttpRequest) string {
+	chatID := chatIDKey(request)
+	if strings.HasPrefix(chatID, "-") || strings.HasPrefix(chatID, "@") {
+		return chatID
+	}
+	return ""
+}
+
+func chatIDKey(request *transport.HttpRequest) string {
+	// uploads are multipart and carry the chat in the query
+	if chatID, ok := request.Params["chat_id"]; ok {
+		return chatID
+	}
+
+	if len(request.Body) == 0 {
+		return ""
+	}
+
+	body := struct {
+		ChatID json.RawMessage `json:"chat_id"`
+	}{}
+	if err := json.Unmarshal(request.Body, &body); err != nil {
+		return ""
+	}
+
+	return strings.Trim(string(body.ChatID), `"`)
+}
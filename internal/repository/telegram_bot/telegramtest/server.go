@@ -0,0 +1,428 @@
+// Package telegramtest runs an in-process fake of the telegram bot api subset
+// the bot uses, for local development and tests.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	*httptest.Server
+	Token string
+
+	lock          sync.Mutex
+	updates       []*Update
+	nextUpdateID  int64
+	nextMessageID int64
+	messages      map[int64][]*Message
+	faults        map[string][]*Fault
+	admins        map[int64]map[int64]bool
+	webhookURL    string
+}
+
+// Message is a message the bot sent, or a message text it edited later
+type Message struct {
+	MessageID int64
+	ChatID    int64
+	Text      string
+	ParseMode string
+	Photo     []byte
+	Filename  string
+	Caption   string
+	Date      time.Time
+	Edited    bool
+}
+
+// Fault is a bot api error returned instead of handling the next call of a method
+type Fault struct {
+	ErrorCode       int
+	Description     string
+	RetryAfter      int64
+	MigrateToChatID int64
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+type Chat struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"`
+}
+
+type Entity struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Type   string `json:"type"`
+}
+
+type UpdateMessage struct {
+	MessageID int64    `json:"message_id"`
+	From      *User    `json:"from"`
+	Chat      *Chat    `json:"chat"`
+	Date      int64    `json:"date"`
+	Text      *string  `json:"text,omitempty"`
+	Entities  []Entity `json:"entities,omitempty"`
+}
+
+type Update struct {
+	UpdateID int64          `json:"update_id"`
+	Message  *UpdateMessage `json:"message,omitempty"`
+}
+
+// NewServer starts a fake bot api server, point TelegramCfg.APIEndpoint at
+// Server.URL and use token as the bot token
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:         token,
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		messages:      make(map[int64][]*Message),
+		faults:        make(map[string][]*Fault),
+		admins:        make(map[int64]map[int64]bool),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// SendText injects a plain text message from fromID in chatID and returns its update id
+func (s *Server) SendText(chatID int64, fromID int64, text string) int64 {
+	return s.addUpdate(chatID, fromID, text, nil)
+}
+
+// SendCommand injects a bot command, e.g. "/arbitrage@gummy_s_bot", and returns its update id
+func (s *Server) SendCommand(chatID int64, fromID int64, command string) int64 {
+	length := len(command)
+	if i := strings.IndexByte(command, ' '); i >= 0 {
+		length = i
+	}
+	return s.addUpdate(chatID, fromID, command, []Entity{{Offset: 0, Length: int64(length), Type: "bot_command"}})
+}
+
+func (s *Server) addUpdate(chatID int64, fromID int64, text string, entities []Entity) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	chatType := "group"
+	if chatID > 0 {
+		chatType = "private"
+	}
+
+	update := &Update{
+		UpdateID: s.nextUpdateID,
+		Message: &UpdateMessage{
+			MessageID: s.nextMessageID,
+			From:      &User{ID: fromID, FirstName: "user" + strconv.FormatInt(fromID, 10)},
+			Chat:      &Chat{ID: chatID, Type: chatType},
+			Date:      time.Now().Unix(),
+			Text:      &text,
+			Entities:  entities,
+		},
+	}
+	s.nextUpdateID++
+	s.nextMessageID++
+
+	s.updates = append(s.updates, update)
+	return update.UpdateID
+}
+
+// Messages returns what the bot sent to chatID, oldest first
+func (s *Server) Messages(chatID int64) []Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	msgs := make([]Message, 0, len(s.messages[chatID]))
+	for _, v := range s.messages[chatID] {
+		msgs = append(msgs, *v)
+	}
+	return msgs
+}
+
+// WebhookURL returns the url set by the last setWebhook call
+func (s *Server) WebhookURL() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.webhookURL
+}
+
+// SetAdmin makes userID an administrator of chatID, everyone else is a member
+func (s *Server) SetAdmin(chatID int64, userID int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.admins[chatID] == nil {
+		s.admins[chatID] = make(map[int64]bool)
+	}
+	s.admins[chatID][userID] = true
+}
+
+// FailNext makes the next call of method, e.g. "sendMessage", fail with fault
+func (s *Server) FailNext(method string, fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[method] = append(s.faults[method], &fault)
+}
+
+func (s *Server) TooManyRequests(method string, retryAfter int64) {
+	s.FailNext(method, Fault{
+		ErrorCode:   http.StatusTooManyRequests,
+		Description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		RetryAfter:  retryAfter,
+	})
+}
+
+func (s *Server) Forbidden(method string) {
+	s.FailNext(method, Fault{
+		ErrorCode:   http.StatusForbidden,
+		Description: "Forbidden: bot was kicked from the group chat",
+	})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 || path[:i] != s.Token {
+		writeError(w, &Fault{ErrorCode: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+	method := path[i+1:]
+
+	if fault := s.popFault(method); fault != nil {
+		writeError(w, fault)
+		return
+	}
+
+	params, photo, filename, err := readParams(r)
+	if err != nil {
+		writeError(w, &Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: " + err.Error()})
+		return
+	}
+
+	switch method {
+	case "getUpdates":
+		s.getUpdates(w, params)
+	case "sendMessage":
+		s.sendMessage(w, params, nil, "")
+	case "sendPhoto":
+		s.sendMessage(w, params, photo, filename)
+	case "getChatMember":
+		s.getChatMember(w, params)
+	case "editMessageText":
+		s.editMessageText(w, params)
+	case "setWebhook":
+		s.lock.Lock()
+		s.webhookURL = params["url"]
+		s.lock.Unlock()
+		writeResult(w, true)
+	default:
+		writeError(w, &Fault{ErrorCode: http.StatusNotFound, Description: "Not Found"})
+	}
+}
+
+func (s *Server) popFault(method string) *Fault {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	faults := s.faults[method]
+	if len(faults) == 0 {
+		return nil
+	}
+	s.faults[method] = faults[1:]
+	return faults[0]
+}
+
+func (s *Server) getUpdates(w http.ResponseWriter, params map[string]string) {
+	offset, _ := strconv.ParseInt(params["offset"], 10, 64)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// like the real api, a positive offset confirms every earlier update
+	if offset > 0 {
+		kept := []*Update{}
+		for _, v := range s.updates {
+			if v.UpdateID >= offset {
+				kept = append(kept, v)
+			}
+		}
+		s.updates = kept
+	}
+
+	writeResult(w, s.updates)
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, params map[string]string, photo []byte, filename string) {
+	chatID, err := strconv.ParseInt(params["chat_id"], 10, 64)
+	if err != nil {
+		writeError(w, &Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: chat not found"})
+		return
+	}
+
+	if photo == nil && len(params["text"]) == 0 {
+		writeError(w, &Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: message text is empty"})
+		return
+	}
+
+	s.lock.Lock()
+	msg := &Message{
+		MessageID: s.nextMessageID,
+		ChatID:    chatID,
+		Text:      params["text"],
+		ParseMode: params["parse_mode"],
+		Photo:     photo,
+		Filename:  filename,
+		Caption:   params["caption"],
+		Date:      time.Now(),
+	}
+	s.nextMessageID++
+	s.messages[chatID] = append(s.messages[chatID], msg)
+	s.lock.Unlock()
+
+	writeResult(w, map[string]interface{}{
+		"message_id": msg.MessageID,
+		"chat":       &Chat{ID: chatID},
+		"date":       msg.Date.Unix(),
+		"text":       msg.Text,
+	})
+}
+
+func (s *Server) getChatMember(w http.ResponseWriter, params map[string]string) {
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	userID, err := strconv.ParseInt(params["user_id"], 10, 64)
+	if err != nil {
+		writeError(w, &Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: invalid user_id specified"})
+		return
+	}
+
+	s.lock.Lock()
+	status := "member"
+	if s.admins[chatID][userID] {
+		status = "administrator"
+	}
+	s.lock.Unlock()
+
+	writeResult(w, map[string]interface{}{
+		"user":   &User{ID: userID, FirstName: "user" + strconv.FormatInt(userID, 10)},
+		"status": status,
+	})
+}
+
+func (s *Server) editMessageText(w http.ResponseWriter, params map[string]string) {
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	messageID, _ := strconv.ParseInt(params["message_id"], 10, 64)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, v := range s.messages[chatID] {
+		if v.MessageID != messageID {
+			continue
+		}
+		v.Text = params["text"]
+		v.ParseMode = params["parse_mode"]
+		v.Edited = true
+		writeResult(w, map[string]interface{}{
+			"message_id": v.MessageID,
+			"chat":       &Chat{ID: chatID},
+			"text":       v.Text,
+		})
+		return
+	}
+
+	writeError(w, &Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: message to edit not found"})
+}
+
+// readParams accepts the same encodings as the real api: query string,
+// json body, url encoded form and multipart form
+func readParams(r *http.Request) (params map[string]string, photo []byte, filename string, err error) {
+	params = map[string]string{}
+	for k, v := range r.URL.Query() {
+		params[k] = v[0]
+	}
+
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		body := map[string]json.RawMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			return nil, nil, "", err
+		}
+		for k, v := range body {
+			var str string
+			if json.Unmarshal(v, &str) == nil {
+				params[k] = str
+			} else {
+				params[k] = string(v)
+			}
+		}
+
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			return nil, nil, "", err
+		}
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+		if f, header, err := r.FormFile("photo"); err == nil {
+			defer f.Close()
+			if photo, err = io.ReadAll(f); err != nil {
+				return nil, nil, "", err
+			}
+			filename = header.Filename
+		}
+
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if err := r.ParseForm(); err != nil {
+			return nil, nil, "", err
+		}
+		for k, v := range r.PostForm {
+			params[k] = v[0]
+		}
+	}
+
+	return params, photo, filename, nil
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": result,
+	})
+}
+
+func writeError(w http.ResponseWriter, fault *Fault) {
+	body := map[string]interface{}{
+		"ok":          false,
+		"error_code":  fault.ErrorCode,
+		"description": fault.Description,
+	}
+
+	parameters := map[string]interface{}{}
+	if fault.RetryAfter > 0 {
+		parameters["retry_after"] = fault.RetryAfter
+		w.Header().Set("Retry-After", strconv.FormatInt(fault.RetryAfter, 10))
+	}
+	if fault.MigrateToChatID != 0 {
+		parameters["migrate_to_chat_id"] = fault.MigrateToChatID
+	}
+	if len(parameters) > 0 {
+		body["parameters"] = parameters
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(fault.ErrorCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
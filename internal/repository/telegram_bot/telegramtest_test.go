@@ -0,0 +1,138 @@
+package telegram_bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/gummy789j/telegram-quote-bot/internal/repository/telegram_bot/telegramtest"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+)
+
+func newFakeBot(t *testing.T, opts ...transport.HttpClientOption) (*telegramtest.Server, domain.TelegramBotRepo) {
+	t.Helper()
+	srv := telegramtest.NewServer(testToken)
+	t.Cleanup(srv.Close)
+
+	cfg := newTestConfig(t)
+	cfg.APIEndpoint = srv.URL
+	return srv, NewTelegramBotRepo(transport.NewHttpClient(opts...), cfg)
+}
+
+func TestTooManyRequestsIsRetriedAfterRetryAfter(t *testing.T) {
+	srv, repo := newFakeBot(t, transport.WithRetryPolicy(transport.DefaultRetryPolicy()))
+	srv.TooManyRequests("sendMessage", 1)
+
+	start := time.Now()
+	if err := repo.SendMessage(context.Background(), domain.SendMessageRequest{ChatID: -1, Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("want the retry to wait retry_after, took %s", elapsed)
+	}
+	if n := len(srv.Messages(-1)); n != 1 {
+		t.Errorf("want 1 message, got %d", n)
+	}
+}
+
+func TestTooManyRequestsBeyondDeadline(t *testing.T) {
+	srv, repo := newFakeBot(t, transport.WithRetryPolicy(transport.DefaultRetryPolicy()))
+	srv.TooManyRequests("sendMessage", 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := repo.SendMessage(ctx, domain.SendMessageRequest{ChatID: -1, Text: "hi"})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("want ErrTooManyRequests, got %v", err)
+	}
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 30*time.Second {
+		t.Errorf("want retry after 30s, got %+v", apiErr)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("want no wait past the deadline, took %s", elapsed)
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		fault telegramtest.Fault
+		want  error
+	}{
+		{"unauthorized", telegramtest.Fault{ErrorCode: http.StatusUnauthorized, Description: "Unauthorized"}, ErrUnauthorized},
+		{"kicked", telegramtest.Fault{ErrorCode: http.StatusForbidden, Description: "Forbidden: bot was kicked from the group chat"}, ErrBotBlocked},
+		{"chat not found", telegramtest.Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: chat not found"}, ErrChatNotFound},
+		{"migrated", telegramtest.Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: group chat was upgraded to a supergroup chat", MigrateToChatID: -1001234}, ErrChatMigrated},
+		{"bad request", telegramtest.Fault{ErrorCode: http.StatusBadRequest, Description: "Bad Request: can't parse entities"}, ErrBadRequest},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			srv, repo := newFakeBot(t)
+			srv.FailNext("sendMessage", c.fault)
+
+			err := repo.SendMessage(context.Background(), domain.SendMessageRequest{ChatID: -1, Text: "hi"})
+			if !errors.Is(err, c.want) {
+				t.Fatalf("want %v, got %v", c.want, err)
+			}
+			apiErr := &APIError{}
+			if !errors.As(err, &apiErr) || apiErr.ErrorCode != c.fault.ErrorCode || apiErr.MigrateToChatID != c.fault.MigrateToChatID {
+				t.Errorf("unexpected api error %+v", apiErr)
+			}
+			statusErr := &transport.HTTPStatusError{}
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != c.fault.ErrorCode {
+				t.Errorf("want the transport error wrapped, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPerChatRateLimit(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := telegramtest.NewServer(testToken)
+	defer srv.Close()
+	cfg.APIEndpoint = srv.URL
+	repo := NewTelegramBotRepo(transport.NewHttpClient(transport.WithRateLimit(RateLimitRule(cfg))), cfg)
+
+	busy, quiet := int64(-100), int64(-200)
+	burst := int(RateLimitRule(cfg).PerKey.Burst)
+	for i := 0; i < burst; i++ {
+		if err := repo.SendMessage(context.Background(), domain.SendMessageRequest{ChatID: busy, Text: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the burst of the busy chat is used up, the next token is seconds away
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err := repo.SendMessage(ctx, domain.SendMessageRequest{ChatID: busy, Text: "one too many"})
+	if !errors.Is(err, transport.ErrRateLimitExceeded) {
+		t.Fatalf("want ErrRateLimitExceeded, got %v", err)
+	}
+
+	// another chat has its own bucket
+	if err := repo.SendMessage(ctx, domain.SendMessageRequest{ChatID: quiet, Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Messages(busy)); n != burst {
+		t.Errorf("want %d messages in the busy chat, got %d", burst, n)
+	}
+	if n := len(srv.Messages(quiet)); n != 1 {
+		t.Errorf("want 1 message in the quiet chat, got %d", n)
+	}
+
+	// a private chat is only held to the total
+	private := int64(6040823283)
+	for i := 0; i < burst+1; i++ {
+		if err := repo.SendMessage(ctx, domain.SendMessageRequest{ChatID: private, Text: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(srv.Messages(private)); n != burst+1 {
+		t.Errorf("want %d messages in the private chat, got %d", burst+1, n)
+	}
+}
@@ -5,10 +5,17 @@ import "fmt"
 type TextTemplate string
 
 var (
-	tmplArbitrageNotifySimple TextTemplate = `<strong>Spread: </strong><u>%s &#128060;</u>
+	tmplArbitrageNotifySimple TextTemplate = `<strong>%s</strong>
+	<strong>Spread: </strong><u>%s &#128060;</u>
 	<strong>%s Buy: </strong><u>%s</u>
 	<strong>%s Sell: </strong><u>%s</u>
 	<strong>Arbitrage: </strong><u>%s</u>
+	<strong>Effective Arbitrage: </strong><u>%s</u>
+	<strong>Net Arbitrage: </strong><u>%s</u>
+	<strong>Fees: </strong><u>%s</u>
+	<strong>VWAP: </strong><u>%s</u>
+	<strong>Max Size: </strong><u>%s</u>
+	<strong>Quote Age: </strong><u>%s</u>
 	<strong>Author: </strong><a href="tg://user?id=%s">%s</a>
 	`
 
@@ -21,6 +28,38 @@ var (
 <strong>Arbitrage: </strong><u>%s</u>
 <strong>Estimated Profit: </strong><u>%s</u>
 <strong>Author: </strong><a href="tg://user?id=%s">%s</a>
+`
+
+	tmplCycleNotify TextTemplate = `<strong>%s</strong>
+	<strong>Net Arbitrage: </strong><u>%s</u>
+	<strong>Invest: </strong><u>%s</u>
+<pre>%s</pre>
+	<strong>Bank Fees: </strong><u>%s</u>
+	<strong>Quote Age: </strong><u>%s</u>
+	<strong>Author: </strong><a href="tg://user?id=%s">%s</a>
+	`
+
+	tmplArbitrageRankNotify TextTemplate = `<strong>%s Routes</strong>
+<pre>%s</pre>
+<strong>Time: </strong><u>%s</u>
+`
+
+	tmplDepthNotify TextTemplate = `<strong>%s %s Depth</strong>
+<pre>%s</pre>
+<strong>Time: </strong><u>%s</u>
+`
+
+	tmplStatsNotify TextTemplate = `<strong>Stats of the last %s</strong>
+<pre>%s</pre>
+<strong>Routes: </strong><u>top %d of each pair by time above min</u>
+<strong>Min Arbitrage: </strong><u>%s</u>
+<strong>Time: </strong><u>%s</u>
+`
+
+	tmplSettingsNotify TextTemplate = `<strong>Settings</strong>
+<pre>%s</pre>
+<strong>Alerts: </strong><u>%s</u>
+<strong>Time: </strong><u>%s</u>
 `
 
 	tmplErrorNotify TextTemplate = `<strong> Error Notification </strong>
@@ -45,6 +84,16 @@ func (t TextTemplate) Type() TemplateType {
 		return HTML
 	case tmplArbitrageNotify:
 		return HTML
+	case tmplCycleNotify:
+		return HTML
+	case tmplArbitrageRankNotify:
+		return HTML
+	case tmplDepthNotify:
+		return HTML
+	case tmplStatsNotify:
+		return HTML
+	case tmplSettingsNotify:
+		return HTML
 	case tmplErrorNotify:
 		return HTML
 	default:
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
+)
+
+type cycleNotifyTask struct {
+	cfg *config.TelegramCfg
+	tb  domain.TelegramUseCase
+}
+
+// NewCycleNotifyTask looks for multi hop arbitrage cycles, it does nothing while MaxCycleHops is below 3
+func NewCycleNotifyTask(cfg *config.TelegramCfg, tb domain.TelegramUseCase) Task {
+	return &cycleNotifyTask{cfg: cfg, tb: tb}
+}
+
+func (t *cycleNotifyTask) Name() string {
+	return "cycle-notify"
+}
+
+// Freq checks the cycles every minute until shutdown
+func (t *cycleNotifyTask) Freq() (runTime time.Duration, tickTime time.Duration) {
+	return 0, time.Minute
+}
+
+func (t *cycleNotifyTask) Run(ctx context.Context) error {
+
+	toChatID := t.cfg.QuoteComparisonBot.GroupChatID
+	if config.IsDevelopment() {
+		toChatID = t.cfg.QuoteComparisonBot.TestGroupChatID
+	}
+
+	err := t.tb.NotifyCycleArbitrage(ctx, domain.NotifyCycleArbitrageRequest{
+		ToChatID: toChatID,
+		Cooldown: t.cfg.QuoteComparisonBot.CycleAlertCooldown,
+	})
+	if err != nil {
+		log.Println("notify cycle arbitrage job failed: ", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+type historyCompactTask struct {
+	cfg     *config.HistoryCfg
+	history dRepo.HistoryRepo
+}
+
+// NewHistoryCompactTask downsamples quote history that is past its retention
+func NewHistoryCompactTask(cfg *config.HistoryCfg, history dRepo.HistoryRepo) Task {
+	return &historyCompactTask{cfg: cfg, history: history}
+}
+
+func (t *historyCompactTask) Name() string {
+	return "history compact"
+}
+
+// Freq compacts every CompactInterval until shutdown
+func (t *historyCompactTask) Freq() (runTime time.Duration, tickTime time.Duration) {
+	return 0, t.cfg.CompactInterval
+}
+
+func (t *historyCompactTask) Run(ctx context.Context) error {
+
+	if err := t.history.Compact(ctx, time.Now()); err != nil {
+		log.Println("compact quote history job failed: ", err.Error())
+	}
+
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/config"
-	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
 )
 
@@ -23,29 +22,30 @@ func (t *notifyTask) Name() string {
 	return "notify"
 }
 
-//NOTE: expired at 2025/12/31 23:59:59
+// Freq checks the watched pairs every minute until shutdown
 func (t *notifyTask) Freq() (runTime time.Duration, tickTime time.Duration) {
-	expiredAt := time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC)
-	return time.Until(expiredAt), time.Minute
+	return 0, time.Minute
 }
 
 func (t *notifyTask) Run(ctx context.Context) error {
 
-	exchangeBuy := constant.Rybit
-	exchangeSell := constant.MAX
-
 	toChatID := t.cfg.QuoteComparisonBot.GroupChatID
 	if config.IsDevelopment() {
 		toChatID = t.cfg.QuoteComparisonBot.TestGroupChatID
 	}
 
-	err := t.tb.NotifyArbitrage(ctx, domain.NotifyArbitrageRequest{
-		ExchangeBuy:  exchangeBuy,
-		ExchangeSell: exchangeSell,
-		ToChatID:     toChatID,
-	})
-	if err != nil {
-		log.Println("notify arbitrage job failed: ", err.Error())
+	for _, v := range t.cfg.QuoteComparisonBot.Pairs {
+		if !v.Notify {
+			continue
+		}
+
+		err := t.tb.NotifyArbitrage(ctx, domain.NotifyArbitrageRequest{
+			Pair:     v.Pair,
+			ToChatID: toChatID,
+		})
+		if err != nil {
+			log.Println("notify arbitrage job failed: ", v.Pair, err.Error())
+		}
 	}
 
 	return nil
@@ -22,10 +22,9 @@ func (t *replyTask) Name() string {
 	return "notify"
 }
 
-//NOTE: expired at 2025/12/31 23:59:59
+// Freq polls the bot updates every 2 seconds until shutdown
 func (t *replyTask) Freq() (runTime time.Duration, tickTime time.Duration) {
-	expiredAt := time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC)
-	return time.Until(expiredAt), 2 * time.Second
+	return 0, 2 * time.Second
 }
 
 func (t *replyTask) Run(ctx context.Context) error {
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	domain "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
+)
+
+type streamNotifyTask struct {
+	cfg     *config.TelegramCfg
+	tb      domain.TelegramUseCase
+	changes <-chan dRepo.QuoteKey
+}
+
+// NewStreamNotifyTask runs the arbitrage check of a pair as soon as a streamed
+// price of it changes, instead of waiting for the next poll
+func NewStreamNotifyTask(cfg *config.TelegramCfg, tb domain.TelegramUseCase, changes <-chan dRepo.QuoteKey) Task {
+	return &streamNotifyTask{cfg: cfg, tb: tb, changes: changes}
+}
+
+func (t *streamNotifyTask) Name() string {
+	return "stream-notify"
+}
+
+// Freq runs the task until shutdown, Run itself only returns when ctx is done
+func (t *streamNotifyTask) Freq() (runTime time.Duration, tickTime time.Duration) {
+	return 0, time.Second
+}
+
+// Run consumes price changes until ctx is done, the changes of a pair are
+// coalesced into at most one check per StreamCheckInterval
+func (t *streamNotifyTask) Run(ctx context.Context) error {
+
+	toChatID := t.cfg.QuoteComparisonBot.GroupChatID
+	if config.IsDevelopment() {
+		toChatID = t.cfg.QuoteComparisonBot.TestGroupChatID
+	}
+
+	interval := t.cfg.QuoteComparisonBot.StreamCheckInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := make(map[*config.PairCfg]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case key := <-t.changes:
+			for _, v := range t.cfg.QuoteComparisonBot.Pairs {
+				if v.Notify && v.Pair == key.Pair {
+					pending[v] = true
+				}
+			}
+
+		case <-ticker.C:
+			for v := range pending {
+				err := t.tb.NotifyArbitrage(ctx, domain.NotifyArbitrageRequest{
+					Pair:     v.Pair,
+					ToChatID: toChatID,
+					Cooldown: t.cfg.QuoteComparisonBot.StreamAlertCooldown,
+				})
+				if err != nil {
+					log.Println("stream notify arbitrage job failed: ", v.Pair, err.Error())
+				}
+			}
+			pending = make(map[*config.PairCfg]bool)
+		}
+	}
+}
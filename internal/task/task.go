@@ -7,6 +7,8 @@ import (
 
 type Task interface {
 	Name() string
+	// Freq tells how long the task is run, a zero runTime runs it until shutdown,
+	// and how often Run is called again within that window
 	Freq() (runTime time.Duration, tickTime time.Duration)
 	Run(ctx context.Context) error
 }
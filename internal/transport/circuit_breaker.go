@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState string
+
+var (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+func (s CircuitState) String() string {
+	return string(s)
+}
+
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before letting probes through
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probes allowed while half-open,
+	// the circuit closes once all of them succeed
+	HalfOpenMaxRequests int
+	IsFailure           func(resp *HttpResponse, err error) bool
+
+	// OnOpen fires once when a closed circuit opens, OnClose fires once when it
+	// recovers, a failed probe while half-open does not fire OnOpen again. Both run
+	// in their own goroutine so that a slow callback never holds up the request.
+	OnOpen  func(endpoint string, err error)
+	OnClose func(endpoint string, downtime time.Duration)
+}
+
+func DefaultCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		FailureThreshold:    5,
+		OpenTimeout:         time.Minute,
+		HalfOpenMaxRequests: 1,
+		IsFailure:           isUpstreamFailure,
+	}
+}
+
+// isUpstreamFailure only counts errors that say the endpoint is unhealthy, 4xx
+// responses are caused by the request itself and a 429 by how often it is sent,
+// the retries back off on those instead
+func isUpstreamFailure(resp *HttpResponse, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimitExceeded) {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type circuitBreakerClient struct {
+	next     HttpClient
+	settings CircuitBreakerSettings
+
+	lock     sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+var _ HttpClient = (*circuitBreakerClient)(nil)
+
+// NewCircuitBreakerClient wraps next with one circuit breaker per endpoint host
+func NewCircuitBreakerClient(next HttpClient, settings CircuitBreakerSettings) HttpClient {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenMaxRequests <= 0 {
+		settings.HalfOpenMaxRequests = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = isUpstreamFailure
+	}
+
+	return &circuitBreakerClient{
+		next:     next,
+		settings: settings,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+func (c *circuitBreakerClient) Send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	u, err := url.Parse(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	cb := c.breaker(u.Host)
+
+	if err := cb.allow(time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.next.Send(ctx, request)
+	// a call the caller gave up on says nothing about the endpoint
+	if err != nil && ctx.Err() != nil {
+		cb.abandon()
+		return resp, err
+	}
+	cb.done(time.Now(), c.settings.IsFailure(resp, err), err)
+
+	return resp, err
+}
+
+func (c *circuitBreakerClient) breaker(endpoint string) *circuitBreaker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cb, ok := c.breakers[endpoint]
+	if !ok {
+		cb = &circuitBreaker{endpoint: endpoint, settings: &c.settings, state: CircuitClosed}
+		c.breakers[endpoint] = cb
+	}
+	return cb
+}
+
+type circuitBreaker struct {
+	endpoint string
+	settings *CircuitBreakerSettings
+
+	lock      sync.Mutex
+	state     CircuitState
+	failures  int
+	probes    int
+	successes int
+	openedAt  time.Time
+	retryAt   time.Time
+}
+
+func (cb *circuitBreaker) allow(now time.Time) error {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.state == CircuitOpen {
+		if now.Before(cb.retryAt) {
+			return fmt.Errorf("%w: %s until %s", ErrCircuitOpen, cb.endpoint, cb.retryAt.Format(time.RFC3339))
+		}
+		cb.state = CircuitHalfOpen
+		cb.probes = 0
+		cb.successes = 0
+	}
+
+	if cb.state == CircuitHalfOpen {
+		if cb.probes >= cb.settings.HalfOpenMaxRequests {
+			return fmt.Errorf("%w: %s is probing", ErrCircuitOpen, cb.endpoint)
+		}
+		cb.probes++
+	}
+
+	return nil
+}
+
+// abandon gives back the probe slot of a call that ended without telling whether
+// the endpoint is healthy, the state stays as it is
+func (cb *circuitBreaker) abandon() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+func (cb *circuitBreaker) done(now time.Time, failed bool, err error) {
+	var onOpen, onClose bool
+	var downtime time.Duration
+
+	cb.lock.Lock()
+	switch cb.state {
+	case CircuitClosed:
+		if !failed {
+			cb.failures = 0
+			break
+		}
+		cb.failures++
+		if cb.failures >= cb.settings.FailureThreshold {
+			cb.state = CircuitOpen
+			cb.openedAt = now
+			cb.retryAt = now.Add(cb.settings.OpenTimeout)
+			onOpen = true
+		}
+
+	case CircuitHalfOpen:
+		if failed {
+			cb.state = CircuitOpen
+			cb.retryAt = now.Add(cb.settings.OpenTimeout)
+			break
+		}
+		cb.successes++
+		if cb.successes >= cb.settings.HalfOpenMaxRequests {
+			cb.state = CircuitClosed
+			cb.failures = 0
+			downtime = now.Sub(cb.openedAt)
+			onClose = true
+		}
+	}
+	cb.lock.Unlock()
+
+	if onOpen && cb.settings.OnOpen != nil {
+		go cb.settings.OnOpen(cb.endpoint, err)
+	}
+	if onClose && cb.settings.OnClose != nil {
+		go cb.settings.OnClose(cb.endpoint, downtime)
+	}
+}
@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type sendFunc func(ctx context.Context, request *HttpRequest) (*HttpResponse, error)
+
+func (f sendFunc) Send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	return f(ctx, request)
+}
+
+func TestCircuitBreakerCanceledProbeStaysHalfOpen(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusBadGateway)
+	next := sendFunc(func(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		code := int(status.Load())
+		resp := &HttpResponse{StatusCode: code}
+		return resp, statusError(resp)
+	})
+
+	closed := make(chan time.Duration, 1)
+	settings := CircuitBreakerSettings{
+		FailureThreshold: 1,
+		OpenTimeout:      10 * time.Millisecond,
+		OnClose:          func(endpoint string, downtime time.Duration) { closed <- downtime },
+	}
+	cli := NewCircuitBreakerClient(next, settings)
+	req := &HttpRequest{Method: http.MethodGet, URL: "http://upstream.test/ticker"}
+
+	if _, err := cli.Send(context.Background(), req); err == nil {
+		t.Fatal("want the upstream failure")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// the probe is canceled by the caller, the circuit must not close on it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cli.Send(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	select {
+	case <-closed:
+		t.Fatal("a canceled probe closed the circuit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the probe slot was given back, the next probe still reaches the upstream
+	status.Store(http.StatusOK)
+	if _, err := cli.Send(context.Background(), req); err != nil {
+		t.Fatalf("want the probe through, got %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("want OnClose after a successful probe")
+	}
+}
+
+func TestCircuitBreakerCallbacksDoNotBlock(t *testing.T) {
+	next := sendFunc(func(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+		resp := &HttpResponse{StatusCode: http.StatusServiceUnavailable}
+		return resp, statusError(resp)
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	cli := NewCircuitBreakerClient(next, CircuitBreakerSettings{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		OnOpen:           func(endpoint string, err error) { <-release },
+	})
+
+	done := make(chan struct{})
+	go func() {
+		cli.Send(context.Background(), &HttpRequest{Method: http.MethodGet, URL: "http://upstream.test/ticker"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Send waited for OnOpen")
+	}
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	status := func(code int) *HttpResponse { return &HttpResponse{StatusCode: code} }
+	tests := []struct {
+		name string
+		resp *HttpResponse
+		err  error
+		want bool
+	}{
+		{"success", status(http.StatusOK), nil, false},
+		{"no response", nil, errors.New("connection refused"), true},
+		{"server error", status(http.StatusBadGateway), statusError(status(http.StatusBadGateway)), true},
+		{"bad request", status(http.StatusBadRequest), statusError(status(http.StatusBadRequest)), false},
+		{"rate limited by the upstream", status(http.StatusTooManyRequests), statusError(status(http.StatusTooManyRequests)), false},
+		{"rate limited locally", nil, ErrRateLimitExceeded, false},
+		{"canceled", nil, context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUpstreamFailure(tt.resp, tt.err); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxErrorBodySize bounds the body kept in HTTPStatusError
+const maxErrorBodySize = 512
+
+// HTTPStatusError is returned by Send together with the response for status >= 400
+type HTTPStatusError struct {
+	StatusCode int
+	Headers    http.Header
+	// Body is truncated to maxErrorBodySize, the full body is in HttpResponse.Body.
+	// It is left out of Error, upstream error pages are html and end up in logs and notifications.
+	Body []byte
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.StatusCode)
+}
+
+func statusError(resp *HttpResponse) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+
+	body := resp.Body
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers,
+		Body:       append([]byte(nil), body...),
+	}
+}
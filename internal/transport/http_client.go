@@ -19,6 +19,9 @@ type HttpRequest struct {
 	Params  map[string]string
 	Cookies map[string]string
 	Body    []byte
+	// Idempotent lets the retry policy resend a POST or PATCH that has no side
+	// effects, e.g. a search, as it would a GET
+	Idempotent bool
 }
 
 type HttpResponse struct {
@@ -33,7 +36,23 @@ type HttpClient interface {
 }
 
 type httpClient struct {
-	client *http.Client
+	client       *http.Client
+	limiter      *rateLimiter
+	interceptors []Interceptor
+	chained      SendFunc
+}
+
+type HttpClientOption func(c *httpClient)
+
+func WithProxy(proxy string) HttpClientOption {
+	return func(c *httpClient) {
+		proxyURL, _ := url.Parse(proxy)
+		c.client.Transport.(*http.Transport).Proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) HttpClientOption {
+	return WithInterceptors(RetryInterceptor(policy))
 }
 
 var _ HttpClient = (*httpClient)(nil)
@@ -53,36 +72,36 @@ func defaultTransport() *http.Transport {
 	}
 }
 
-func NewHttpClient(proxy ...string) HttpClient {
-	transport := defaultTransport()
-
-	if len(proxy) > 0 {
-		proxy, _ := url.Parse(proxy[0])
-		transport.Proxy = http.ProxyURL(proxy)
-	}
-
-	return &httpClient{
-		client: &http.Client{
-			Transport: transport,
-			Timeout:   30 * time.Second,
-		},
-	}
+func NewHttpClient(opts ...HttpClientOption) HttpClient {
+	return newHttpClient(defaultTransport(), opts...)
 }
 
 // Use this only when you know the target server is trustable
-func NewHttpsClientUnsecure() HttpClient {
+func NewHttpsClientUnsecure(opts ...HttpClientOption) HttpClient {
 	transport := defaultTransport()
 	// #nosec
 	transport.TLSClientConfig = &tls.Config{
 		InsecureSkipVerify: true,
 	}
 
-	return &httpClient{
+	return newHttpClient(transport, opts...)
+}
+
+func newHttpClient(transport *http.Transport, opts ...HttpClientOption) *httpClient {
+	c := &httpClient{
 		client: &http.Client{
 			Transport: transport,
 			Timeout:   30 * time.Second,
 		},
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.chained = chain(c.interceptors, c.send)
+
+	return c
 }
 
 func GetAPIPath(endpoint, path string) string {
@@ -90,6 +109,16 @@ func GetAPIPath(endpoint, path string) string {
 }
 
 func (c *httpClient) Send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	return c.chained(ctx, request)
+}
+
+func (c *httpClient) send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx, request); err != nil {
+			return nil, err
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, request.Method, request.URL, bytes.NewBuffer(request.Body))
 	if err != nil {
 		return nil, err
@@ -122,10 +151,6 @@ func (c *httpClient) Send(ctx context.Context, request *HttpRequest) (*HttpRespo
 		return nil, err
 	}
 
-	if resp.StatusCode >= 400 {
-		err = fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	response := &HttpResponse{
 		Headers:    resp.Header,
 		Cookies:    resp.Cookies(),
@@ -133,5 +158,5 @@ func (c *httpClient) Send(ctx context.Context, request *HttpRequest) (*HttpRespo
 		StatusCode: resp.StatusCode,
 	}
 
-	return response, err
+	return response, statusError(response)
 }
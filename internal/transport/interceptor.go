@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type SendFunc func(ctx context.Context, request *HttpRequest) (*HttpResponse, error)
+
+// Interceptor runs around a Send call, it can inspect or change the request,
+// decide whether to call next and inspect or change the response and error
+type Interceptor func(ctx context.Context, request *HttpRequest, next SendFunc) (*HttpResponse, error)
+
+// WithInterceptors appends interceptors to the client, the first one is the outermost
+func WithInterceptors(interceptors ...Interceptor) HttpClientOption {
+	return func(c *httpClient) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+type chainClient struct {
+	next         HttpClient
+	interceptors []Interceptor
+}
+
+var _ HttpClient = (*chainClient)(nil)
+
+// Chain wraps any HttpClient, e.g. a recorder or circuit breaker, with interceptors
+func Chain(next HttpClient, interceptors ...Interceptor) HttpClient {
+	return &chainClient{next: next, interceptors: interceptors}
+}
+
+func (c *chainClient) Send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	return chain(c.interceptors, c.next.Send)(ctx, request)
+}
+
+func chain(interceptors []Interceptor, send SendFunc) SendFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], send
+		send = func(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+			return interceptor(ctx, request, next)
+		}
+	}
+	return send
+}
+
+type RequestStats struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// MetricsInterceptor reports every call to observe, the url has bot tokens redacted
+func MetricsInterceptor(observe func(stats RequestStats)) Interceptor {
+	return func(ctx context.Context, request *HttpRequest, next SendFunc) (*HttpResponse, error) {
+		start := time.Now()
+		resp, err := next(ctx, request)
+
+		stats := RequestStats{
+			Method:   request.Method,
+			URL:      RedactBotToken(request.URL),
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		if resp != nil {
+			stats.StatusCode = resp.StatusCode
+		}
+		observe(stats)
+
+		return resp, err
+	}
+}
+
+// LoggingInterceptor logs failed calls, or every call when verbose is set
+func LoggingInterceptor(verbose bool) Interceptor {
+	return MetricsInterceptor(func(stats RequestStats) {
+		if stats.Err != nil {
+			log.Printf("http %s %s failed after %s: %s", stats.Method, stats.URL, stats.Duration, RedactBotToken(stats.Err.Error()))
+			return
+		}
+		if verbose {
+			log.Printf("http %s %s %d in %s", stats.Method, stats.URL, stats.StatusCode, stats.Duration)
+		}
+	})
+}
+
+// RedactInterceptor rewrites error messages so that secrets in urls, which
+// net/http puts into its errors, never reach logs or admin notifications
+func RedactInterceptor(redactors ...Redactor) Interceptor {
+	if len(redactors) == 0 {
+		redactors = []Redactor{RedactBotToken}
+	}
+
+	return func(ctx context.Context, request *HttpRequest, next SendFunc) (*HttpResponse, error) {
+		resp, err := next(ctx, request)
+		if err == nil {
+			return resp, nil
+		}
+
+		msg := err.Error()
+		for _, redactor := range redactors {
+			msg = redactor(msg)
+		}
+		return resp, &redactedError{msg: msg, err: err}
+	}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RetryInterceptor retries the rest of the chain according to policy
+func RetryInterceptor(policy RetryPolicy) Interceptor {
+	return func(ctx context.Context, request *HttpRequest, next SendFunc) (*HttpResponse, error) {
+		return policy.sendWithRetry(ctx, request, next)
+	}
+}
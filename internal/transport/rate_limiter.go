@@ -0,0 +1,191 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var ErrRateLimitExceeded = errors.New("rate limit wait exceeds context deadline")
+
+type RateLimit struct {
+	// Rate is the number of requests allowed per second
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+type RateLimitRule struct {
+	Host   string
+	Global RateLimit
+	// PerKey adds one more bucket for every key returned by KeyFunc, e.g. a telegram chat_id,
+	// requests with an empty key are only limited by Global
+	PerKey  RateLimit
+	KeyFunc func(request *HttpRequest) string
+}
+
+func WithRateLimit(rules ...RateLimitRule) HttpClientOption {
+	return func(c *httpClient) {
+		c.limiter = newRateLimiter(rules...)
+	}
+}
+
+type rateLimiter struct {
+	hosts map[string]*hostLimiter
+}
+
+func newRateLimiter(rules ...RateLimitRule) *rateLimiter {
+	l := &rateLimiter{hosts: make(map[string]*hostLimiter)}
+	for _, rule := range rules {
+		h := &hostLimiter{rule: rule, perKey: make(map[string]*tokenBucket)}
+		if rule.Global.enabled() {
+			h.global = newTokenBucket(rule.Global)
+		}
+		l.hosts[rule.Host] = h
+	}
+	return l
+}
+
+// Wait blocks until the request is allowed to go out, it fails fast when the
+// wait would outlive the context deadline
+func (l *rateLimiter) Wait(ctx context.Context, request *HttpRequest) error {
+	u, err := url.Parse(request.URL)
+	if err != nil {
+		return err
+	}
+
+	h, ok := l.hosts[u.Hostname()]
+	if !ok {
+		return nil
+	}
+
+	buckets := h.buckets(request)
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, b := range buckets {
+		if d := b.reserve(now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		for _, b := range buckets {
+			b.cancel()
+		}
+		return ErrRateLimitExceeded
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		for _, b := range buckets {
+			b.cancel()
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type hostLimiter struct {
+	rule   RateLimitRule
+	global *tokenBucket
+
+	lock   sync.Mutex
+	perKey map[string]*tokenBucket
+}
+
+func (h *hostLimiter) buckets(request *HttpRequest) []*tokenBucket {
+	buckets := []*tokenBucket{}
+	if h.global != nil {
+		buckets = append(buckets, h.global)
+	}
+
+	if !h.rule.PerKey.enabled() || h.rule.KeyFunc == nil {
+		return buckets
+	}
+
+	key := h.rule.KeyFunc(request)
+	if len(key) == 0 {
+		return buckets
+	}
+
+	h.lock.Lock()
+	b, ok := h.perKey[key]
+	if !ok {
+		b = newTokenBucket(h.rule.PerKey)
+		h.perKey[key] = b
+	}
+	h.lock.Unlock()
+
+	return append(buckets, b)
+}
+
+// tokenBucket lets tokens go negative so that callers queue up in order, each
+// reservation returns how long the caller has to wait for its token
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token reserved by a caller that is not going to send
+func (b *tokenBucket) cancel() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
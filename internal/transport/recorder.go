@@ -0,0 +1,259 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var ErrInteractionNotFound = errors.New("no recorded interaction matches the request")
+
+type RecorderMode string
+
+var (
+	// RecorderReplay never touches the network, unknown requests fail with ErrInteractionNotFound
+	RecorderReplay RecorderMode = "replay"
+	// RecorderRecord always sends through the real client and rewrites the cassette
+	RecorderRecord RecorderMode = "record"
+	// RecorderReplayOrRecord replays known requests and records the rest
+	RecorderReplayOrRecord RecorderMode = "replay_or_record"
+)
+
+// Redactor rewrites a url or body before it is written to or matched against a cassette
+type Redactor func(s string) string
+
+// RedactBotToken hides telegram bot tokens, e.g. https://api.telegram.org/bot123:abc/sendMessage
+func RedactBotToken(s string) string {
+	return botTokenPattern.ReplaceAllString(s, "/bot***")
+}
+
+var botTokenPattern = regexp.MustCompile(`/bot\d+:[A-Za-z0-9_-]+`)
+
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+
+	replayed bool
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+type RecorderOption func(r *recorderClient)
+
+func WithRedactors(redactors ...Redactor) RecorderOption {
+	return func(r *recorderClient) {
+		r.redactors = append(r.redactors, redactors...)
+	}
+}
+
+type recorderClient struct {
+	path      string
+	mode      RecorderMode
+	next      HttpClient
+	redactors []Redactor
+
+	lock     sync.Mutex
+	cassette *Cassette
+}
+
+var _ HttpClient = (*recorderClient)(nil)
+
+// NewRecorderClient records the traffic of next into the cassette file at path
+// or replays it from there, next can be nil in RecorderReplay mode.
+// Bot tokens are always redacted.
+func NewRecorderClient(path string, mode RecorderMode, next HttpClient, opts ...RecorderOption) (HttpClient, error) {
+	r := &recorderClient{
+		path:      path,
+		mode:      mode,
+		next:      next,
+		redactors: []Redactor{RedactBotToken},
+		cassette:  &Cassette{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode != RecorderReplay && next == nil {
+		return nil, fmt.Errorf("recorder in %s mode needs a client", mode)
+	}
+
+	if mode == RecorderRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && mode == RecorderReplayOrRecord {
+			return r, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, r.cassette); err != nil {
+		return nil, fmt.Errorf("load cassette %s failed: %w", path, err)
+	}
+
+	return r, nil
+}
+
+func (r *recorderClient) Send(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+	recorded := r.recordedRequest(request)
+
+	if r.mode != RecorderRecord {
+		if interaction := r.match(recorded); interaction != nil {
+			return replay(interaction)
+		}
+		if r.mode == RecorderReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, recorded.Method, recorded.URL)
+		}
+	}
+
+	resp, err := r.next.Send(ctx, request)
+	if resp == nil {
+		// nothing came back from the server, there is nothing to replay
+		return resp, err
+	}
+
+	if err := r.record(recorded, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, err
+}
+
+func (r *recorderClient) recordedRequest(request *HttpRequest) RecordedRequest {
+	u := request.URL
+	if len(request.Params) > 0 {
+		// reuse the same encoding as the real request so that param order does not matter
+		req, err := http.NewRequest(request.Method, request.URL, nil)
+		if err == nil {
+			query := req.URL.Query()
+			for k, v := range request.Params {
+				query.Add(k, v)
+			}
+			req.URL.RawQuery = query.Encode()
+			u = req.URL.String()
+		}
+	}
+
+	return RecordedRequest{
+		Method: request.Method,
+		URL:    r.redact(u),
+		Body:   r.redact(normalizeBody(request.Body)),
+	}
+}
+
+func (r *recorderClient) redact(s string) string {
+	for _, redactor := range r.redactors {
+		s = redactor(s)
+	}
+	return s
+}
+
+// match returns the first matching interaction that has not been replayed yet,
+// or the last matching one when all of them were used, so polling keeps working
+func (r *recorderClient) match(req RecordedRequest) *Interaction {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var last *Interaction
+	for _, v := range r.cassette.Interactions {
+		if v.Request != req {
+			continue
+		}
+		if !v.replayed {
+			v.replayed = true
+			return v
+		}
+		last = v
+	}
+	return last
+}
+
+func (r *recorderClient) record(req RecordedRequest, resp *HttpResponse) error {
+	headers := resp.Headers.Clone()
+	headers.Del("Set-Cookie")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: req,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    headers,
+			Body:       r.redact(string(resp.Body)),
+		},
+		replayed: true,
+	})
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.cassette); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, buf.Bytes(), 0o644)
+}
+
+func replay(interaction *Interaction) (*HttpResponse, error) {
+	resp := &HttpResponse{
+		Headers:    interaction.Response.Headers,
+		StatusCode: interaction.Response.StatusCode,
+		Body:       []byte(interaction.Response.Body),
+	}
+	if resp.Headers == nil {
+		resp.Headers = http.Header{}
+	}
+
+	return resp, statusError(resp)
+}
+
+// normalizeBody makes json bodies comparable regardless of key order and whitespace
+func normalizeBody(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return string(body)
+	}
+
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
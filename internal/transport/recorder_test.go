@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRedactsAndReplays(t *testing.T) {
+	const token = "123456:SECRET-token"
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"result":{"url":"` + r.URL.Path + `"}}`))
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	request := &HttpRequest{
+		Method: http.MethodPost,
+		URL:    upstream.URL + "/bot" + token + "/sendMessage",
+		Body:   []byte(`{"text":"hi",  "chat_id":1}`),
+	}
+
+	recorder, err := NewRecorderClient(path, RecorderRecord, NewHttpClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Send(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "SECRET") {
+		t.Fatalf("cassette leaks the token:\n%s", data)
+	}
+	if !strings.Contains(string(data), "/bot***/sendMessage") {
+		t.Fatalf("cassette misses the redacted url:\n%s", data)
+	}
+
+	// replay matches regardless of the body key order and never hits the network
+	upstream.Close()
+	replayer, err := NewRecorderClient(path, RecorderReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Body = []byte(`{"chat_id":1,"text":"hi"}`)
+	resp, err := replayer.Send(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(resp.Body), `"ok":true`) {
+		t.Fatalf("unexpected replay %d %s", resp.StatusCode, resp.Body)
+	}
+}
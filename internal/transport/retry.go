@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, values <= 1 disable retrying
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Multiplier  float64
+	// Jitter is the fraction of the delay that is randomized, e.g. 0.2 means +-20%
+	Jitter float64
+	// RetryNonIdempotent allows retrying POST/PATCH on 5xx and broken connections, a single
+	// request opts in with HttpRequest.Idempotent. 429 and dial failures are always retried
+	// because the server never processed them
+	RetryNonIdempotent bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+	}
+}
+
+var (
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterLock sync.Mutex
+)
+
+// backoff returns the delay before the next attempt, attempt starts from 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		jitterLock.Lock()
+		delay += delay * p.Jitter * (2*jitterRand.Float64() - 1)
+		jitterLock.Unlock()
+	}
+
+	return time.Duration(delay)
+}
+
+func (p RetryPolicy) shouldRetry(ctx context.Context, request *HttpRequest, resp *HttpResponse, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	retryable := p.RetryNonIdempotent || request.Idempotent || isIdempotent(request.Method)
+
+	if resp == nil {
+		// the request never reached the server, safe for any method
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return retryable
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return retryable
+	default:
+		return false
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter reads the delay requested by the server, either from the Retry-After
+// header or from telegram's {"parameters":{"retry_after":N}} response body
+func retryAfter(resp *HttpResponse) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if v := resp.Headers.Get("Retry-After"); len(v) > 0 {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			d := time.Until(t)
+			if d < 0 {
+				d = 0
+			}
+			return d, true
+		}
+	}
+
+	body := struct {
+		Parameters *struct {
+			RetryAfter int64 `json:"retry_after"`
+		} `json:"parameters"`
+	}{}
+	if err := json.Unmarshal(resp.Body, &body); err == nil && body.Parameters != nil && body.Parameters.RetryAfter > 0 {
+		return time.Duration(body.Parameters.RetryAfter) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// sendWithRetry keeps calling send until it succeeds, the policy gives up or
+// the next wait would outlive the context deadline
+func (p RetryPolicy) sendWithRetry(ctx context.Context, request *HttpRequest, send func(context.Context, *HttpRequest) (*HttpResponse, error)) (*HttpResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := send(ctx, request)
+		if err == nil {
+			return resp, nil
+		}
+
+		if attempt >= p.MaxAttempts || !p.shouldRetry(ctx, request, resp, err) {
+			return resp, err
+		}
+
+		delay := p.backoff(attempt)
+		if d, ok := retryAfter(resp); ok {
+			delay = d
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
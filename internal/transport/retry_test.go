@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryIdempotentPost(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	for _, c := range []struct {
+		idempotent bool
+		wantCalls  int32
+	}{
+		{false, 1},
+		{true, int32(policy.MaxAttempts)},
+	} {
+		calls := atomic.Int32{}
+		send := func(ctx context.Context, request *HttpRequest) (*HttpResponse, error) {
+			calls.Add(1)
+			resp := &HttpResponse{StatusCode: http.StatusBadGateway}
+			return resp, statusError(resp)
+		}
+
+		request := &HttpRequest{Method: http.MethodPost, URL: "http://stand.in/search", Idempotent: c.idempotent}
+		if _, err := policy.sendWithRetry(context.Background(), request, send); err == nil {
+			t.Fatal("want the 502 returned")
+		}
+		if n := calls.Load(); n != c.wantCalls {
+			t.Errorf("idempotent %v: want %d calls, got %d", c.idempotent, c.wantCalls, n)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var ErrHeartbeatTimeout = errors.New("websocket heartbeat timed out")
+
+type WebSocketConfig struct {
+	URL string
+	// Subscriptions are json encoded and sent in order after every connect
+	Subscriptions []interface{}
+	// PingInterval is how often a ping is sent, the connection is dropped when
+	// nothing, pongs included, arrives within HeartbeatTimeout
+	PingInterval     time.Duration
+	HeartbeatTimeout time.Duration
+	// Backoff spaces out reconnects, MaxAttempts is ignored since reconnecting never gives up
+	Backoff RetryPolicy
+}
+
+func DefaultWebSocketConfig(url string) WebSocketConfig {
+	return WebSocketConfig{
+		URL:              url,
+		PingInterval:     15 * time.Second,
+		HeartbeatTimeout: 45 * time.Second,
+		Backoff: RetryPolicy{
+			BaseDelay:  time.Second,
+			MaxDelay:   time.Minute,
+			Multiplier: 2,
+			Jitter:     0.2,
+		},
+	}
+}
+
+// RunWebSocket keeps a connection to cfg.URL and passes every message to handle
+// until ctx is done. A dropped or silent connection is redialed with backoff and
+// subscribed again, connected is called after every successful subscribe.
+func RunWebSocket(ctx context.Context, cfg WebSocketConfig, handle func(msg []byte), connected func()) error {
+	attempt := 0
+	for {
+		gotMessage, err := runWebSocketOnce(ctx, cfg, handle, connected)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// a connection that worked starts the backoff over
+		if gotMessage {
+			attempt = 0
+		}
+		attempt++
+
+		delay := cfg.Backoff.backoff(attempt)
+		log.Printf("websocket %s disconnected, reconnecting in %s: %v", cfg.URL, delay.Truncate(time.Millisecond), err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func runWebSocketOnce(ctx context.Context, cfg WebSocketConfig, handle func(msg []byte), connected func()) (gotMessage bool, err error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, cfg.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	for _, v := range cfg.Subscriptions {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return false, err
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return false, fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	if connected != nil {
+		connected()
+	}
+
+	alive := func() error {
+		if cfg.HeartbeatTimeout <= 0 {
+			return nil
+		}
+		return conn.SetReadDeadline(time.Now().Add(cfg.HeartbeatTimeout))
+	}
+	if err := alive(); err != nil {
+		return false, err
+	}
+	conn.SetPongHandler(func(string) error { return alive() })
+
+	// the reader owns the connection reads, this goroutine pings and closes on cancel
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		var pings <-chan time.Time
+		if cfg.PingInterval > 0 {
+			ticker := time.NewTicker(cfg.PingInterval)
+			defer ticker.Stop()
+			pings = ticker.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-pings:
+				deadline := time.Now().Add(10 * time.Second)
+				if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = ErrHeartbeatTimeout
+			}
+			return gotMessage, err
+		}
+		gotMessage = true
+		if err := alive(); err != nil {
+			return gotMessage, err
+		}
+		handle(msg)
+	}
+}
@@ -0,0 +1,147 @@
+// Package wstest runs an in-process websocket stand-in for exchange feeds,
+// for local development and tests of streaming quote sources.
+package wstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type Server struct {
+	*httptest.Server
+
+	lock       sync.Mutex
+	conns      map[*websocket.Conn]bool
+	received   [][]byte
+	connects   int
+	ignorePing bool
+	onMessage  func(msg []byte) [][]byte
+}
+
+// NewServer starts a websocket server, clients connect to Server.URL()
+func NewServer() *Server {
+	s := &Server{conns: make(map[*websocket.Conn]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the ws:// url of the server
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.Server.URL, "http")
+}
+
+// OnMessage sets the replies to a client message, e.g. a subscription snapshot
+func (s *Server) OnMessage(reply func(msg []byte) [][]byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onMessage = reply
+}
+
+// Broadcast sends msg to every connected client
+func (s *Server) Broadcast(msg string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for conn := range s.conns {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(msg))
+	}
+}
+
+// Drop closes every connection without a close frame, like a network failure
+func (s *Server) Drop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for conn := range s.conns {
+		conn.UnderlyingConn().Close()
+		delete(s.conns, conn)
+	}
+}
+
+// Silence stops answering pings so that clients hit their heartbeat timeout
+func (s *Server) Silence(silent bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ignorePing = silent
+}
+
+// Received returns every message clients sent, oldest first
+func (s *Server) Received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	msgs := make([]string, 0, len(s.received))
+	for _, v := range s.received {
+		msgs = append(msgs, string(v))
+	}
+	return msgs
+}
+
+// Connects returns how many connections were accepted so far
+func (s *Server) Connects() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connects
+}
+
+// WaitConnects waits until n connections were accepted or timeout passes
+func (s *Server) WaitConnects(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if s.Connects() >= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.conns[conn] = true
+	s.connects++
+	s.lock.Unlock()
+
+	conn.SetPingHandler(func(data string) error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.ignorePing {
+			return nil
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		s.received = append(s.received, msg)
+		var replies [][]byte
+		if s.onMessage != nil {
+			replies = s.onMessage(msg)
+		}
+		for _, v := range replies {
+			_ = conn.WriteMessage(websocket.TextMessage, v)
+		}
+		s.lock.Unlock()
+	}
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+)
+
+// alertCooldown remembers when each route or cycle last alerted a chat
+type alertCooldown struct {
+	lock sync.Mutex
+	last map[string]time.Time
+}
+
+func newAlertCooldown() *alertCooldown {
+	return &alertCooldown{last: make(map[string]time.Time)}
+}
+
+func (c *alertCooldown) ready(key string, cooldown time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return cooldown <= 0 || time.Since(c.last[key]) >= cooldown
+}
+
+func (c *alertCooldown) mark(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.last[key] = time.Now()
+}
@@ -0,0 +1,350 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	dUc "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
+	"github.com/shopspring/decimal"
+)
+
+// maxCycleAlerts caps the cycles alerted per run, the best ones first
+const maxCycleAlerts = 3
+
+// cycleNode is holding an asset on an exchange
+type cycleNode struct {
+	Exchange constant.Exchange
+	Asset    constant.Symbol
+}
+
+// rateEdge is one trade of the rate graph, trading In into Out on the exchange of both
+type rateEdge struct {
+	Key  dRepo.QuoteKey
+	Buy  bool
+	In   cycleNode
+	Out  cycleNode
+	Rate decimal.Decimal
+	// Price is the quote the rate comes from, ask for a buy and bid for a sell
+	Price   decimal.Decimal
+	Taker   decimal.Decimal
+	Updated dRepo.QuotationInfo
+}
+
+// trade returns what amount of In becomes, trading fee taken, and the fee in Out
+func (e rateEdge) trade(amount decimal.Decimal) (out decimal.Decimal, fee decimal.Decimal) {
+	gross := amount.Mul(e.Rate)
+	fee = gross.Mul(e.Taker)
+	return gross.Sub(fee), fee
+}
+
+// rateGraph holds the trades leaving each node. Moving an asset between exchanges
+// is not an edge of its own, it is folded into the next trade with its withdrawal fee.
+type rateGraph struct {
+	edges map[cycleNode][]rateEdge
+}
+
+// newRateGraph turns every quote in qInfo but those in skip into a buy and a sell edge,
+// the rates are top of book
+func newRateGraph(cfg *config.TelegramCfg, qInfo *dRepo.GetQuotationsResponse, skip map[dRepo.QuoteKey]bool) *rateGraph {
+	g := &rateGraph{edges: make(map[cycleNode][]rateEdge)}
+	for key, info := range qInfo.Infos {
+		if skip[key] || !info.BuyPrice.IsPositive() || !info.SellPrice.IsPositive() {
+			continue
+		}
+		taker := cfg.QuoteComparisonBot.FeesOf(key.Exchange).TakerFee
+		base := cycleNode{Exchange: key.Exchange, Asset: key.Pair.Base}
+		quote := cycleNode{Exchange: key.Exchange, Asset: key.Pair.Quote}
+
+		g.edges[quote] = append(g.edges[quote], rateEdge{
+			Key: key, Buy: true, In: quote, Out: base,
+			Rate: decimal.NewFromInt(1).Div(info.BuyPrice), Price: info.BuyPrice, Taker: taker, Updated: info,
+		})
+		g.edges[base] = append(g.edges[base], rateEdge{
+			Key: key, Buy: false, In: base, Out: quote,
+			Rate: info.SellPrice, Price: info.SellPrice, Taker: taker, Updated: info,
+		})
+	}
+
+	// stable walks give stable alerts
+	for node := range g.edges {
+		edges := g.edges[node]
+		sort.Slice(edges, func(i, j int) bool {
+			if edges[i].Key != edges[j].Key {
+				return edges[i].Key.String() < edges[j].Key.String()
+			}
+			return edges[i].Buy
+		})
+	}
+	return g
+}
+
+// next returns the trades of asset leaving from, on the exchange of from or any
+// other one after withdrawing asset to it
+func (g *rateGraph) next(from cycleNode) []rateEdge {
+	edges := []rateEdge{}
+	for node, v := range g.edges {
+		if node.Asset != from.Asset {
+			continue
+		}
+		// TWD only moves through the bank at both ends of a cycle
+		if node.Exchange != from.Exchange && from.Asset == constant.TWD {
+			continue
+		}
+		edges = append(edges, v...)
+	}
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].In.Exchange < edges[j].In.Exchange })
+	return edges
+}
+
+// arbitrageCycle is a path of trades that starts and ends in TWD
+type arbitrageCycle struct {
+	Invest       decimal.Decimal
+	Return       decimal.Decimal
+	NetProfit    decimal.Decimal
+	NetArbitrage decimal.Decimal
+	DepositFee   decimal.Decimal
+	WithdrawFee  decimal.Decimal
+	Hops         []dRepo.ArbitrageHop
+	// Infos are the quotes each hop traded on
+	Infos []dRepo.QuotationInfo
+}
+
+func (c arbitrageCycle) String() string {
+	parts := make([]string, 0, len(c.Hops))
+	for _, v := range c.Hops {
+		side := "sell"
+		if v.Buy {
+			side = "buy"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", v.Exchange, v.Pair, side))
+	}
+	return strings.Join(parts, " > ")
+}
+
+// findCycles walks the graph from TWD on every exchange and returns the cycles of
+// 3 to maxHops trades back into TWD, ranked by net arbitrage. Two trades are a plain
+// route which scanRoutes covers. Amounts are carried through every hop so that flat
+// fees, the bank and withdrawal ones, count for the invest they are paid on.
+func findCycles(cfg *config.TelegramCfg, g *rateGraph, invest decimal.Decimal, maxHops int) []arbitrageCycle {
+	starts := []constant.Exchange{}
+	for node := range g.edges {
+		if node.Asset == constant.TWD {
+			starts = append(starts, node.Exchange)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	cycles := []arbitrageCycle{}
+	for _, exchange := range starts {
+		depositFee := cfg.QuoteComparisonBot.FeesOf(exchange).TWDDepositFee
+		start := cycleNode{Exchange: exchange, Asset: constant.TWD}
+		amount := invest.Sub(depositFee)
+		if !amount.IsPositive() {
+			continue
+		}
+
+		hops := []dRepo.ArbitrageHop{}
+		infos := []dRepo.QuotationInfo{}
+		visited := map[cycleNode]bool{start: true}
+
+		var walk func(at cycleNode, amount decimal.Decimal)
+		walk = func(at cycleNode, amount decimal.Decimal) {
+			for _, e := range g.next(at) {
+				// never pass the same holding twice, TWD ends the cycle on any exchange
+				moved := e.In != at
+				if moved && visited[e.In] || e.Out.Asset != constant.TWD && visited[e.Out] {
+					continue
+				}
+
+				// withdraw to the exchange of the trade first
+				withdrawal := decimal.Zero
+				if moved {
+					withdrawal = cfg.QuoteComparisonBot.FeesOf(at.Exchange).WithdrawalFee[at.Asset][cfg.QuoteComparisonBot.NetworkOf(at.Asset)]
+				}
+				in := amount.Sub(withdrawal)
+				if !in.IsPositive() {
+					continue
+				}
+				out, fee := e.trade(in)
+
+				hops = append(hops, dRepo.ArbitrageHop{
+					Exchange:      e.Key.Exchange,
+					Pair:          e.Key.Pair,
+					Buy:           e.Buy,
+					Price:         e.Price,
+					AssetIn:       e.In.Asset,
+					AmountIn:      in,
+					AssetOut:      e.Out.Asset,
+					AmountOut:     out,
+					TradingFee:    fee,
+					WithdrawalFee: withdrawal,
+				})
+				infos = append(infos, e.Updated)
+
+				switch {
+				case e.Out.Asset == constant.TWD:
+					if len(hops) >= 3 {
+						cycles = append(cycles, newArbitrageCycle(cfg, invest, depositFee, e.Out.Exchange, out, hops, infos))
+					}
+				case len(hops) < maxHops:
+					visited[e.In], visited[e.Out] = true, true
+					walk(e.Out, out)
+					visited[e.Out] = false
+					if moved {
+						visited[e.In] = false
+					}
+				}
+
+				hops, infos = hops[:len(hops)-1], infos[:len(infos)-1]
+			}
+		}
+		walk(start, amount)
+	}
+
+	sort.SliceStable(cycles, func(i, j int) bool {
+		a, b := cycles[i].NetArbitrage, cycles[j].NetArbitrage
+		if !a.Equal(b) {
+			return a.GreaterThan(b)
+		}
+		return cycles[i].String() < cycles[j].String()
+	})
+	return cycles
+}
+
+// newArbitrageCycle closes a walk that ended with amount of TWD on exchange
+func newArbitrageCycle(cfg *config.TelegramCfg, invest decimal.Decimal, depositFee decimal.Decimal, exchange constant.Exchange, amount decimal.Decimal, hops []dRepo.ArbitrageHop, infos []dRepo.QuotationInfo) arbitrageCycle {
+	withdrawFee := cfg.QuoteComparisonBot.FeesOf(exchange).TWDWithdrawalFee
+	ret := amount.Sub(withdrawFee)
+	return arbitrageCycle{
+		Invest:       invest,
+		Return:       ret,
+		NetProfit:    ret.Sub(invest),
+		NetArbitrage: ret.Sub(invest).Div(invest),
+		DepositFee:   depositFee,
+		WithdrawFee:  withdrawFee,
+		Hops:         append([]dRepo.ArbitrageHop{}, hops...),
+		Infos:        append([]dRepo.QuotationInfo{}, infos...),
+	}
+}
+
+// NotifyCycleArbitrage builds the rate graph of every watched pair and alerts each
+// subscribed chat about the best cycles clearing its MinArbitrage net of every fee
+func (u *telegramUseCase) NotifyCycleArbitrage(ctx context.Context, req dUc.NotifyCycleArbitrageRequest) error {
+	var err error
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil && !errors.Is(err, transport.ErrCircuitOpen) {
+			u.notifyError(ctx, "NotifyCycleArbitrage", err.Error())
+		}
+	}()
+
+	maxHops := u.cfg.QuoteComparisonBot.MaxCycleHops
+	if maxHops < 3 {
+		return nil
+	}
+
+	qInfo, err := u.cycleQuotations(ctx)
+	if err != nil {
+		log.Println("get cycle quotations failed: ", err.Error())
+		return err
+	}
+
+	// stale quotes are not worth an alert
+	stale := u.checkStaleQuotes(ctx, qInfo)
+
+	// every chat alerts with its own thresholds, one chat failing does not keep the others waiting
+	for _, chat := range u.alertChats(ctx, req.ToChatID) {
+		if chatErr := u.notifyChatCycles(ctx, req, chat, qInfo, stale, maxHops); chatErr != nil {
+			log.Println("notify cycle arbitrage failed: ", chat.ChatID, chatErr.Error())
+			err = chatErr
+		}
+	}
+	return err
+}
+
+func (u *telegramUseCase) notifyChatCycles(ctx context.Context, req dUc.NotifyCycleArbitrageRequest, chat *dRepo.ChatSettings, qInfo *dRepo.GetQuotationsResponse, stale map[dRepo.QuoteKey]bool, maxHops int) error {
+	cfg := chatConfig(u.cfg, chat)
+	alertKey := func(c arbitrageCycle) string {
+		return fmt.Sprintf("%d cycle %s", chat.ChatID, c)
+	}
+
+	sent := 0
+	for _, c := range findCycles(cfg, newRateGraph(cfg, qInfo, stale), cfg.QuoteComparisonBot.DefaultInvest, maxHops) {
+		if sent >= maxCycleAlerts || c.NetArbitrage.LessThan(cfg.QuoteComparisonBot.MinArbitrage) {
+			break
+		}
+		// a cycle that stays open alerts once per cooldown, the next best ones take its place
+		if !u.alert.ready(alertKey(c), req.Cooldown) {
+			continue
+		}
+		if err := u.tb.SendArbitrageNotify(ctx, cycleNotifyRequest(cfg, chat.ChatID, qInfo, c)); err != nil {
+			log.Println("send cycle arbitrage notify failed: ", err.Error())
+			return err
+		}
+		u.alert.mark(alertKey(c))
+		sent++
+	}
+	return nil
+}
+
+// cycleNotifyRequest is the alert of a cycle, the first and last hop stand in for the buy and sell legs
+func cycleNotifyRequest(cfg *config.TelegramCfg, chatID int64, qInfo *dRepo.GetQuotationsResponse, c arbitrageCycle) dRepo.SendArbitrageNotifyRequest {
+	first, last := c.Hops[0], c.Hops[len(c.Hops)-1]
+	return dRepo.SendArbitrageNotifyRequest{
+		ChatID:             chatID,
+		Pair:               first.Pair,
+		InvestAmount:       c.Invest,
+		ExchangeBuy:        first.Exchange,
+		ExchangeSell:       last.Exchange,
+		BuyPrice:           first.Price,
+		SellPrice:          last.Price,
+		NetArbitrage:       c.NetArbitrage,
+		NetProfit:          c.NetProfit,
+		BuyFees:            dRepo.LegFees{Bank: c.DepositFee},
+		SellFees:           dRepo.LegFees{Bank: c.WithdrawFee},
+		QuoteAge:           qInfo.Age(),
+		BuyQuoteAge:        c.Infos[0].Age(),
+		SellQuoteAge:       c.Infos[len(c.Infos)-1].Age(),
+		IsExcitedArbitrage: c.NetArbitrage.GreaterThanOrEqual(cfg.QuoteComparisonBot.ExcitedArbitrage),
+		Hops:               c.Hops,
+	}
+}
+
+// cycleQuotations merges the quotes of every watched pair into one response,
+// a pair failing to quote only leaves its edges out
+func (u *telegramUseCase) cycleQuotations(ctx context.Context) (*dRepo.GetQuotationsResponse, error) {
+	merged := &dRepo.GetQuotationsResponse{Infos: make(map[dRepo.QuoteKey]dRepo.QuotationInfo)}
+
+	var lastErr error
+	for _, v := range u.cfg.QuoteComparisonBot.Pairs {
+		qInfo, err := u.quote.GetQuotations(ctx, dRepo.NewGetQuotationsRequest(v.Pair))
+		if err != nil {
+			log.Println("get quotations failed: ", v.Pair, err.Error())
+			lastErr = err
+			continue
+		}
+		for key, info := range qInfo.Infos {
+			merged.Infos[key] = info
+		}
+		// the oldest snapshot is the age of the merged one
+		if merged.FetchedAt.IsZero() || qInfo.FetchedAt.Before(merged.FetchedAt) {
+			merged.FetchedAt = qInfo.FetchedAt
+		}
+	}
+
+	if len(merged.Infos) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return merged, nil
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	dUc "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	btcTWD  = constant.Pair{Base: constant.BTC, Quote: constant.TWD}
+	btcUSDT = constant.Pair{Base: constant.BTC, Quote: constant.USDT}
+	btcUSDC = constant.Pair{Base: constant.BTC, Quote: constant.USDC}
+	usdcTWD = constant.Pair{Base: constant.USDC, Quote: constant.TWD}
+)
+
+// cycleQuotes is a small market where buying USDT on MAX, BTC with it on BitoPro and
+// selling the BTC back on MAX makes about 3.3%, every other way round loses
+func cycleQuotes() *dRepo.GetQuotationsResponse {
+	quote := func(buy, sell string) dRepo.QuotationInfo {
+		return dRepo.QuotationInfo{BuyPrice: decimal.RequireFromString(buy), SellPrice: decimal.RequireFromString(sell), UpdateTime: time.Now()}
+	}
+	return &dRepo.GetQuotationsResponse{
+		Infos: map[dRepo.QuoteKey]dRepo.QuotationInfo{
+			{Exchange: constant.MAX, Pair: constant.USDTTWD}:     quote("30", "29.9"),
+			{Exchange: constant.BitoPro, Pair: constant.USDTTWD}: quote("31.5", "29.8"),
+			{Exchange: constant.BitoPro, Pair: btcUSDT}:          quote("20000", "19990"),
+			{Exchange: constant.MAX, Pair: btcTWD}:               quote("630000", "620000"),
+			// only a 4 hop cycle gets through USDC, and it loses
+			{Exchange: constant.MAX, Pair: usdcTWD}:     quote("31", "29"),
+			{Exchange: constant.BitoPro, Pair: btcUSDC}: quote("21000", "20000"),
+		},
+		FetchedAt: time.Now(),
+	}
+}
+
+// cycleConfig is the default config without fees, so the rates alone decide
+func cycleConfig(t *testing.T) *config.TelegramCfg {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123456:TEST-token_abc")
+	cfg := config.NewConfig(true).Telegram
+	cfg.QuoteComparisonBot.Fees = nil
+	return cfg
+}
+
+func TestFindCycles(t *testing.T) {
+	best := "MAX USDT/TWD buy > BitoPro BTC/USDT buy > MAX BTC/TWD sell"
+
+	tests := []struct {
+		name    string
+		maxHops int
+		fees    map[constant.Exchange]*config.FeeSchedule
+		// best is the top cycle when it clears, empty when none does
+		best string
+		// hops are the cycle lengths found
+		hops []int
+	}{
+		{name: "profitable 3 hop cycle", maxHops: 3, best: best, hops: []int{3}},
+		{name: "two trades are a route, not a cycle", maxHops: 2},
+		{name: "4 hops reach USDC", maxHops: 4, best: best, hops: []int{3, 4}},
+		{
+			name:    "taker fees eat the cycle",
+			maxHops: 3,
+			fees:    map[constant.Exchange]*config.FeeSchedule{constant.MAX: {TakerFee: decimal.RequireFromString("0.02")}},
+			hops:    []int{3},
+		},
+		{
+			name:    "withdrawal fees eat the cycle",
+			maxHops: 3,
+			fees: map[constant.Exchange]*config.FeeSchedule{constant.BitoPro: {WithdrawalFee: map[constant.Symbol]map[constant.Network]decimal.Decimal{
+				constant.BTC: {constant.Bitcoin: decimal.RequireFromString("0.05")},
+			}}},
+			hops: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cycleConfig(t)
+			cfg.QuoteComparisonBot.Fees = tt.fees
+			cycles := findCycles(cfg, newRateGraph(cfg, cycleQuotes(), nil), decimal.NewFromInt(600000), tt.maxHops)
+
+			if tt.best != "" && (len(cycles) == 0 || cycles[0].String() != tt.best || !cycles[0].NetArbitrage.IsPositive()) {
+				t.Fatalf("want %s first and profitable, got %v", tt.best, cycles)
+			}
+			if tt.best == "" {
+				for _, c := range cycles {
+					if c.NetArbitrage.IsPositive() {
+						t.Errorf("want no profitable cycle, got %s at %s", c, c.NetArbitrage)
+					}
+				}
+			}
+
+			lengths := map[int]bool{}
+			for _, c := range cycles {
+				if len(c.Hops) > tt.maxHops {
+					t.Errorf("%s has more than %d hops", c, tt.maxHops)
+				}
+				lengths[len(c.Hops)] = true
+			}
+			if len(lengths) != len(tt.hops) {
+				t.Errorf("want cycles of %v hops, got %v", tt.hops, lengths)
+			}
+			for _, v := range tt.hops {
+				if !lengths[v] {
+					t.Errorf("want a cycle of %d hops, got %v", v, lengths)
+				}
+			}
+
+			// the same trades in another order would be a rotation of a reported cycle
+			seen := map[string]string{}
+			for _, c := range cycles {
+				trades := strings.Split(c.String(), " > ")
+				sort.Strings(trades)
+				key := strings.Join(trades, ",")
+				if other, ok := seen[key]; ok {
+					t.Errorf("%s is reported again as %s", other, c)
+				}
+				seen[key] = c.String()
+			}
+		})
+	}
+}
+
+func TestNewRateGraph(t *testing.T) {
+	cfg := cycleConfig(t)
+	stale := map[dRepo.QuoteKey]bool{{Exchange: constant.BitoPro, Pair: btcUSDC}: true}
+	g := newRateGraph(cfg, cycleQuotes(), stale)
+
+	twd := g.edges[cycleNode{Exchange: constant.MAX, Asset: constant.TWD}]
+	if len(twd) != 3 {
+		t.Fatalf("want a buy edge of each TWD market on MAX, got %+v", twd)
+	}
+	for _, e := range twd {
+		if !e.Buy || e.Out.Exchange != constant.MAX || !e.Rate.Equal(decimal.NewFromInt(1).Div(e.Price)) {
+			t.Errorf("unexpected edge %+v", e)
+		}
+	}
+
+	// a stale quote trades neither way
+	if edges := g.edges[cycleNode{Exchange: constant.BitoPro, Asset: constant.USDC}]; len(edges) != 0 {
+		t.Errorf("want no edges from the stale quote, got %+v", edges)
+	}
+
+	// USDT on MAX trades there or after a withdrawal to BitoPro, TWD never leaves its exchange
+	if next := g.next(cycleNode{Exchange: constant.MAX, Asset: constant.USDT}); len(next) != 3 {
+		t.Errorf("want USDT to sell on both exchanges and buy BTC on BitoPro, got %+v", next)
+	}
+	if next := g.next(cycleNode{Exchange: constant.BitoPro, Asset: constant.TWD}); len(next) != 1 {
+		t.Errorf("want TWD on BitoPro to only buy USDT there, got %+v", next)
+	}
+}
+
+// fixedQuotes answers every pair with the same snapshot
+type fixedQuotes struct {
+	resp *dRepo.GetQuotationsResponse
+}
+
+func (q *fixedQuotes) GetQuotations(ctx context.Context, req dRepo.GetQuotationsRequest) (*dRepo.GetQuotationsResponse, error) {
+	return q.resp, nil
+}
+
+// noChatSettings is a store no chat saved settings in
+type noChatSettings struct {
+	dRepo.SettingsRepo
+}
+
+func (s noChatSettings) ListChatSettings(ctx context.Context) ([]*dRepo.ChatSettings, error) {
+	return nil, nil
+}
+
+// arbitrageNotifies records the arbitrage alerts, every other call panics
+type arbitrageNotifies struct {
+	dRepo.TelegramBotRepo
+	sent []dRepo.SendArbitrageNotifyRequest
+}
+
+func (n *arbitrageNotifies) SendArbitrageNotify(ctx context.Context, req dRepo.SendArbitrageNotifyRequest) error {
+	n.sent = append(n.sent, req)
+	return nil
+}
+
+func TestNotifyCycleArbitrageCooldown(t *testing.T) {
+	cfg := cycleConfig(t)
+	tb := &arbitrageNotifies{}
+	u := &telegramUseCase{cfg: cfg, tb: tb, quote: &fixedQuotes{resp: cycleQuotes()}, settings: noChatSettings{}, stale: newStaleTracker(), alert: newAlertCooldown()}
+
+	req := dUc.NotifyCycleArbitrageRequest{ToChatID: cfg.QuoteComparisonBot.TestGroupChatID, Cooldown: time.Hour}
+	for i := 0; i < 2; i++ {
+		if err := u.NotifyCycleArbitrage(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tb.sent) != 1 || tb.sent[0].ChatID != req.ToChatID || len(tb.sent[0].Hops) != 3 {
+		t.Fatalf("want the cycle alerted once within the cooldown, got %+v", tb.sent)
+	}
+
+	// without a cooldown every run alerts
+	req.Cooldown = 0
+	if err := u.NotifyCycleArbitrage(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.sent) != 2 {
+		t.Fatalf("want the cycle alerted again without a cooldown, got %d alerts", len(tb.sent))
+	}
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// tradeFees are the fee schedules of buying an asset on one exchange, moving it
+// over network and selling it on another
+type tradeFees struct {
+	Buy     config.FeeSchedule
+	Sell    config.FeeSchedule
+	Asset   constant.Symbol
+	Quote   constant.Symbol
+	Network constant.Network
+}
+
+// newTradeFees takes the network from the pair config, without one the transfer is free
+func newTradeFees(cfg *config.TelegramCfg, pair constant.Pair, buy constant.Exchange, sell constant.Exchange) tradeFees {
+	fees := tradeFees{
+		Buy:   cfg.QuoteComparisonBot.FeesOf(buy),
+		Sell:  cfg.QuoteComparisonBot.FeesOf(sell),
+		Asset: pair.Base,
+		Quote: pair.Quote,
+	}
+	if pairCfg, ok := cfg.QuoteComparisonBot.PairCfgOf(pair); ok {
+		fees.Network = pairCfg.Network
+	}
+	return fees
+}
+
+// of returns the fees of a fill in the quote currency, the withdrawal is valued at the buy price.
+// Bank fees only apply to TWD, a crypto quote stays on the exchanges.
+func (t tradeFees) of(f fill) (buy dRepo.LegFees, sell dRepo.LegFees) {
+	buy = dRepo.LegFees{
+		Trading:    f.Invest.Mul(t.Buy.TakerFee),
+		Withdrawal: t.Buy.WithdrawalFee[t.Asset][t.Network].Mul(f.BuyPrice),
+	}
+	sell = dRepo.LegFees{
+		Trading: f.Return.Mul(t.Sell.TakerFee),
+	}
+	if t.Quote == constant.TWD {
+		buy.Bank, sell.Bank = t.Buy.TWDDepositFee, t.Sell.TWDWithdrawalFee
+	}
+	return buy, sell
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	"github.com/shopspring/decimal"
+)
+
+func TestTradeFeesBankOnlyOnTWD(t *testing.T) {
+	schedule := config.FeeSchedule{
+		TakerFee:         decimal.RequireFromString("0.001"),
+		TWDDepositFee:    decimal.NewFromInt(15),
+		TWDWithdrawalFee: decimal.NewFromInt(15),
+	}
+	f := fill{
+		Invest:   decimal.NewFromInt(1000),
+		Return:   decimal.NewFromInt(1010),
+		BuyPrice: decimal.NewFromInt(1),
+	}
+
+	for _, c := range []struct {
+		quote constant.Symbol
+		bank  decimal.Decimal
+	}{
+		{constant.TWD, decimal.NewFromInt(15)},
+		{constant.USDT, decimal.Zero},
+	} {
+		fees := tradeFees{Buy: schedule, Sell: schedule, Asset: constant.USDC, Quote: c.quote}
+		buy, sell := fees.of(f)
+		if !buy.Bank.Equal(c.bank) || !sell.Bank.Equal(c.bank) {
+			t.Errorf("%s: want bank fees %s, got %s/%s", c.quote, c.bank, buy.Bank, sell.Bank)
+		}
+		if !buy.Trading.Equal(decimal.NewFromInt(1)) || !sell.Trading.Equal(decimal.RequireFromString("1.01")) {
+			t.Errorf("%s: unexpected trading fees %s/%s", c.quote, buy.Trading, sell.Trading)
+		}
+	}
+}
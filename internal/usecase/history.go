@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/chart"
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// historyRange is a /history window and the resolution that keeps its chart readable
+type historyRange struct {
+	Window     time.Duration
+	Resolution dRepo.Resolution
+}
+
+var (
+	historyRanges = map[string]historyRange{
+		"1h":  {Window: time.Hour, Resolution: dRepo.ResolutionRaw},
+		"24h": {Window: 24 * time.Hour, Resolution: dRepo.ResolutionMinute},
+		"7d":  {Window: 7 * 24 * time.Hour, Resolution: dRepo.ResolutionHour},
+	}
+	defaultHistoryRange = "24h"
+
+	historyChartWidth  = 1000
+	historyChartHeight = 700
+)
+
+type historyCommand struct {
+	cfg     *config.TelegramCfg
+	tb      dRepo.TelegramBotRepo
+	history dRepo.HistoryRepo
+	args    []string
+}
+
+func newHistoryCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, history dRepo.HistoryRepo, args []string) commandHandler {
+	return &historyCommand{cfg: cfg, tb: tb, history: history, args: args}
+}
+
+// Reply handles /history [range] [pair], e.g. /history 7d USDC
+func (c *historyCommand) Reply(toID int64, chatID int64) error {
+	ctx := context.Background()
+
+	rangeName, pair := defaultHistoryRange, c.cfg.QuoteComparisonBot.DefaultPair()
+	for _, arg := range c.args {
+		if _, ok := historyRanges[arg]; ok {
+			rangeName = arg
+			continue
+		}
+		p, ok := constant.LookupPair(arg)
+		if !ok {
+			return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，不認識 %s，只看 1h、24h、7d", arg))
+		}
+		pair = p
+	}
+
+	route, ok := c.cfg.QuoteComparisonBot.PairCfgOf(pair)
+	if !ok {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，沒有在看 %s", pair))
+	}
+
+	r := historyRanges[rangeName]
+	now := time.Now()
+	resp, err := c.history.GetHistory(ctx, dRepo.GetHistoryRequest{
+		Base:       pair.Base,
+		Quote:      pair.Quote,
+		From:       now.Add(-r.Window),
+		To:         now,
+		Resolution: r.Resolution,
+	})
+	if err != nil {
+		log.Println("get history failed: ", err.Error())
+		return err
+	}
+
+	buys, sells := resp.Series(route.ExchangeBuy), resp.Series(route.ExchangeSell)
+	if len(buys) == 0 || len(sells) == 0 {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，%s 最近 %s 還沒有紀錄", pair, rangeName))
+	}
+
+	spreads := spreadSeries(buys, sells)
+	photo, err := historyChart(pair, route, rangeName, buys, sells, spreads).PNG()
+	if err != nil {
+		log.Println("render history chart failed: ", err.Error())
+		return err
+	}
+
+	caption := fmt.Sprintf("%s %s>%s %s", pair, route.ExchangeBuy, route.ExchangeSell, rangeName)
+	if len(spreads) > 0 {
+		lo, hi := spreads[0].Value, spreads[0].Value
+		for _, v := range spreads {
+			if v.Value < lo {
+				lo = v.Value
+			}
+			if v.Value > hi {
+				hi = v.Value
+			}
+		}
+		caption = fmt.Sprintf("%s\nSpread: last %.4f, min %.4f, max %.4f", caption, spreads[len(spreads)-1].Value, lo, hi)
+	}
+
+	return c.tb.SendPhoto(ctx, dRepo.SendPhotoRequest{
+		ChatID:   chatID,
+		Photo:    photo,
+		Filename: fmt.Sprintf("%s-%s-%s.png", pair.Base, pair.Quote, rangeName),
+		Caption:  caption,
+	})
+}
+
+func (c *historyCommand) reply(ctx context.Context, chatID int64, msg string) error {
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+		ChatID: chatID,
+		Text:   msg,
+	})
+}
+
+// spreadSeries is the sell price of the sell leg minus the buy price of the buy leg
+func spreadSeries(buys, sells []dRepo.HistoryPoint) []chart.Point {
+	points := []chart.Point{}
+	for _, v := range routeSamples(buys, sells) {
+		spread, _ := v.Spread.Float64()
+		points = append(points, chart.Point{Time: v.Time, Value: spread})
+	}
+	return points
+}
+
+func historyChart(pair constant.Pair, route *config.PairCfg, rangeName string, buys, sells []dRepo.HistoryPoint, spreads []chart.Point) *chart.Chart {
+	prices := func(points []dRepo.HistoryPoint, sell bool) []chart.Point {
+		series := make([]chart.Point, 0, len(points))
+		for _, v := range points {
+			price := v.Buy.Close
+			if sell {
+				price = v.Sell.Close
+			}
+			f, _ := price.Float64()
+			series = append(series, chart.Point{Time: v.Time, Value: f})
+		}
+		return series
+	}
+
+	return &chart.Chart{
+		Title:  fmt.Sprintf("%s %s > %s %s", pair, route.ExchangeBuy, route.ExchangeSell, rangeName),
+		Width:  historyChartWidth,
+		Height: historyChartHeight,
+		Panels: []chart.Panel{
+			{
+				Title: "Price",
+				Series: []chart.Series{
+					{Name: fmt.Sprintf("%s buy", route.ExchangeBuy), Color: chart.Blue, Points: prices(buys, false)},
+					{Name: fmt.Sprintf("%s sell", route.ExchangeSell), Color: chart.Orange, Points: prices(sells, true)},
+				},
+			},
+			{
+				Title:    "Spread",
+				Series:   []chart.Series{{Name: string(pair.Quote), Color: chart.Green, Points: spreads}},
+				ZeroLine: true,
+			},
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// scannedRoute buys the pair on Buy and sells it on Sell
+type scannedRoute struct {
+	Pair      constant.Pair
+	Buy       constant.Exchange
+	Sell      constant.Exchange
+	BuyInfo   dRepo.QuotationInfo
+	SellInfo  dRepo.QuotationInfo
+	Arbitrage arbitrageInfo
+}
+
+// scanRoutes evaluates every ordered pair of exchanges quoting pair in qInfo and
+// ranks the routes by net arbitrage. Quotes in skip, e.g. stale ones, are left out.
+// Every route is first figured on its top of book, only the routes passing keep
+// are walked through the order books and at most limit of them, zero is no limit.
+func scanRoutes(ctx context.Context, cfg *config.TelegramCfg, depth dRepo.DepthRepo, pair constant.Pair, qInfo *dRepo.GetQuotationsResponse, skip map[dRepo.QuoteKey]bool, keep func(r scannedRoute) bool, limit int) []scannedRoute {
+	invest, minArbitrage := cfg.QuoteComparisonBot.DefaultInvest, cfg.QuoteComparisonBot.MinArbitrage
+
+	quotes := []dRepo.QuoteKey{}
+	for key, info := range qInfo.Infos {
+		if key.Pair == pair && !skip[key] && info.BuyPrice.IsPositive() && info.SellPrice.IsPositive() {
+			quotes = append(quotes, key)
+		}
+	}
+
+	routes := []scannedRoute{}
+	for _, buy := range quotes {
+		for _, sell := range quotes {
+			if buy.Exchange == sell.Exchange {
+				continue
+			}
+			r := scannedRoute{Pair: pair, Buy: buy.Exchange, Sell: sell.Exchange, BuyInfo: qInfo.Infos[buy], SellInfo: qInfo.Infos[sell]}
+			fees := newTradeFees(cfg, pair, r.Buy, r.Sell)
+			r.Arbitrage = calArbitrageInfo(invest, minArbitrage, topOfBook(r.BuyInfo.BuyPrice), topOfBook(r.SellInfo.SellPrice), fees)
+			if keep == nil || keep(r) {
+				routes = append(routes, r)
+			}
+		}
+	}
+
+	rankRoutes(routes)
+	if limit > 0 && len(routes) > limit {
+		routes = routes[:limit]
+	}
+
+	// an exchange is on many routes, fetch its book once
+	books := newDepthMemo(depth)
+	for i, r := range routes {
+		asks, bids := loadOrderBooks(ctx, books, pair, r.Buy, r.BuyInfo.BuyPrice, r.Sell, r.SellInfo.SellPrice)
+		routes[i].Arbitrage = calArbitrageInfo(invest, minArbitrage, asks, bids, newTradeFees(cfg, pair, r.Buy, r.Sell))
+	}
+
+	rankRoutes(routes)
+	return routes
+}
+
+// quoteProblems explains the quotes of pair that were left out of a scan. The legs of the
+// watched route come first as "buy leg" and "sell leg", then every other exchange whose
+// quote was rejected. Quotes in stale are reported with domain.ErrStaleQuote.
+func quoteProblems(cfg *config.TelegramCfg, pair constant.Pair, qInfo *dRepo.GetQuotationsResponse, stale map[dRepo.QuoteKey]bool) []error {
+	problems := []error{}
+	seen := make(map[constant.Exchange]bool)
+
+	if route, ok := cfg.QuoteComparisonBot.PairCfgOf(pair); ok {
+		for _, leg := range []struct {
+			name     string
+			exchange constant.Exchange
+		}{{"buy leg", route.ExchangeBuy}, {"sell leg", route.ExchangeSell}} {
+			seen[leg.exchange] = true
+			info, err := qInfo.Quotation(leg.exchange, pair)
+			if err == nil && stale[dRepo.QuoteKey{Exchange: leg.exchange, Pair: pair}] {
+				err = staleQuoteError(leg.exchange, pair, info)
+			}
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s %w", leg.name, err))
+			}
+		}
+	}
+
+	others := []error{}
+	for key, err := range qInfo.Rejected {
+		if key.Pair == pair && !seen[key.Exchange] {
+			others = append(others, err)
+		}
+	}
+	for key, isStale := range stale {
+		if isStale && key.Pair == pair && !seen[key.Exchange] {
+			others = append(others, staleQuoteError(key.Exchange, pair, qInfo.Infos[key]))
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Error() < others[j].Error() })
+	return append(problems, others...)
+}
+
+func staleQuoteError(exchange constant.Exchange, pair constant.Pair, info dRepo.QuotationInfo) error {
+	return &dRepo.QuoteError{
+		Exchange: exchange,
+		Pair:     pair,
+		Err:      dRepo.ErrStaleQuote,
+		Reason:   fmt.Sprintf("updated %s ago", info.Age().Truncate(time.Second)),
+	}
+}
+
+// joinErrors puts errs on one line
+func joinErrors(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, v := range errs {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// rankRoutes sorts by net arbitrage, best first, ties by route name to keep the order stable
+func rankRoutes(routes []scannedRoute) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := routes[i].Arbitrage.NetArbitrage, routes[j].Arbitrage.NetArbitrage
+		if !a.Equal(b) {
+			return a.GreaterThan(b)
+		}
+		if routes[i].Buy != routes[j].Buy {
+			return routes[i].Buy < routes[j].Buy
+		}
+		return routes[i].Sell < routes[j].Sell
+	})
+}
+
+// depthMemo remembers the books fetched during one scan, nil when next is nil
+type depthMemo struct {
+	next dRepo.DepthRepo
+
+	lock  sync.Mutex
+	books map[dRepo.GetDepthRequest]depthResult
+}
+
+type depthResult struct {
+	resp *dRepo.GetDepthResponse
+	err  error
+}
+
+func newDepthMemo(next dRepo.DepthRepo) dRepo.DepthRepo {
+	if next == nil {
+		return nil
+	}
+	return &depthMemo{next: next, books: make(map[dRepo.GetDepthRequest]depthResult)}
+}
+
+func (m *depthMemo) GetDepth(ctx context.Context, req dRepo.GetDepthRequest) (*dRepo.GetDepthResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if v, ok := m.books[req]; ok {
+		return v.resp, v.err
+	}
+	resp, err := m.next.GetDepth(ctx, req)
+	m.books[req] = depthResult{resp: resp, err: err}
+	return resp, err
+}
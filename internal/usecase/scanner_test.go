@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+func TestQuoteProblems(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123456:TEST-token_abc")
+	cfg := config.NewConfig(true).Telegram
+	// the watched USDT/TWD route buys on Rybit and sells on MAX
+	pair := constant.USDTTWD
+
+	quote := func(buy, sell string, age time.Duration) dRepo.QuotationInfo {
+		return dRepo.QuotationInfo{
+			BuyPrice:   decimal.RequireFromString(buy),
+			SellPrice:  decimal.RequireFromString(sell),
+			UpdateTime: time.Now().Add(-age),
+		}
+	}
+	maxKey := dRepo.QuoteKey{Exchange: constant.MAX, Pair: pair}
+	aceKey := dRepo.QuoteKey{Exchange: constant.ACE, Pair: pair}
+	bitoKey := dRepo.QuoteKey{Exchange: constant.BitoPro, Pair: pair}
+	qInfo := &dRepo.GetQuotationsResponse{
+		Infos: map[dRepo.QuoteKey]dRepo.QuotationInfo{
+			maxKey:  quote("32.45", "32.41", time.Second),
+			bitoKey: quote("32.5", "32.44", time.Hour),
+		},
+		Rejected: map[dRepo.QuoteKey]error{
+			aceKey: dRepo.ValidateQuotation(aceKey, quote("32.3", "32.6", time.Second)),
+		},
+	}
+
+	problems := quoteProblems(cfg, pair, qInfo, map[dRepo.QuoteKey]bool{maxKey: false, bitoKey: true})
+	if len(problems) != 3 {
+		t.Fatalf("want the missing buy leg, the rejected ACE quote and the stale BitoPro one, got %q", joinErrors(problems))
+	}
+	if !strings.HasPrefix(problems[0].Error(), "buy leg Rybit") || !errors.Is(problems[0], dRepo.ErrQuoteMissing) {
+		t.Errorf("want the buy leg missing first, got %v", problems[0])
+	}
+	if !errors.Is(problems[1], dRepo.ErrInvertedQuote) || !errors.Is(problems[2], dRepo.ErrStaleQuote) {
+		t.Errorf("want the rejected then the stale quote, got %q", joinErrors(problems[1:]))
+	}
+
+	// a stale sell leg is named as a leg
+	problems = quoteProblems(cfg, pair, qInfo, map[dRepo.QuoteKey]bool{maxKey: true})
+	if len(problems) != 3 || !strings.HasPrefix(problems[1].Error(), "sell leg MAX") || !errors.Is(problems[1], dRepo.ErrStaleQuote) {
+		t.Errorf("want the stale sell leg second, got %q", joinErrors(problems))
+	}
+}
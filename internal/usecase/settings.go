@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// chatConfig returns cfg with the thresholds the chat set. The spreads replace the bot
+// wide ones only, a pair with its own MinSpread or ExcitedSpread keeps it.
+func chatConfig(cfg *config.TelegramCfg, settings *dRepo.ChatSettings) *config.TelegramCfg {
+	if settings == nil || len(settings.Values) == 0 {
+		return cfg
+	}
+
+	bot := *cfg.QuoteComparisonBot
+	for key, v := range settings.Values {
+		switch key {
+		case dRepo.SettingMinSpread:
+			bot.MinSpread = v
+		case dRepo.SettingMinArbitrage:
+			bot.MinArbitrage = v
+		case dRepo.SettingExcitedSpread:
+			bot.ExcitedSpread = v
+		case dRepo.SettingExcitedArbitrage:
+			bot.ExcitedArbitrage = v
+		case dRepo.SettingDefaultInvest:
+			bot.DefaultInvest = v
+		}
+	}
+	chat := *cfg
+	chat.QuoteComparisonBot = &bot
+	return &chat
+}
+
+// settingOf returns the value of key in cfg
+func settingOf(cfg *config.TelegramCfg, key dRepo.SettingKey) decimal.Decimal {
+	switch key {
+	case dRepo.SettingMinSpread:
+		return cfg.QuoteComparisonBot.MinSpread
+	case dRepo.SettingMinArbitrage:
+		return cfg.QuoteComparisonBot.MinArbitrage
+	case dRepo.SettingExcitedSpread:
+		return cfg.QuoteComparisonBot.ExcitedSpread
+	case dRepo.SettingExcitedArbitrage:
+		return cfg.QuoteComparisonBot.ExcitedArbitrage
+	case dRepo.SettingDefaultInvest:
+		return cfg.QuoteComparisonBot.DefaultInvest
+	default:
+		return decimal.Zero
+	}
+}
+
+// configOf is the config of the chat, the bot wide one when its settings cannot be read
+func (u *telegramUseCase) configOf(ctx context.Context, chatID int64) *config.TelegramCfg {
+	settings, err := u.settings.GetChatSettings(ctx, chatID)
+	if err != nil {
+		log.Println("get chat settings failed: ", chatID, err.Error())
+		return u.cfg
+	}
+	return chatConfig(u.cfg, settings)
+}
+
+// parseSettingValue accepts ratios as fractions or percents, e.g. 0.005 or 0.5%
+func parseSettingValue(key dRepo.SettingKey, s string) (decimal.Decimal, bool) {
+	percent := key.IsRatio() && strings.HasSuffix(s, "%")
+	v, err := decimal.NewFromString(strings.TrimSuffix(s, "%"))
+	if err != nil || v.IsNegative() {
+		return decimal.Decimal{}, false
+	}
+	if percent {
+		v = v.Shift(-2)
+	}
+	if key == dRepo.SettingDefaultInvest && !v.IsPositive() {
+		return decimal.Decimal{}, false
+	}
+	return v, true
+}
+
+type setCommand struct {
+	cfg      *config.TelegramCfg
+	tb       dRepo.TelegramBotRepo
+	settings dRepo.SettingsRepo
+	args     []string
+}
+
+func newSetCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, settings dRepo.SettingsRepo, args []string) commandHandler {
+	return &setCommand{cfg: cfg, tb: tb, settings: settings, args: args}
+}
+
+// Reply handles /set <key> <value>, e.g. /set min_arbitrage 0.8% or /set min_spread default
+// to go back to the bot wide value. Only chat admins may change a group, the values apply
+// to the alerts once the chat runs /subscribe.
+func (c *setCommand) Reply(toID int64, chatID int64) error {
+	ctx := context.Background()
+
+	if len(c.args) != 2 {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，要這樣用 /set <key> <value>，key 有 %s", settingKeyNames()))
+	}
+
+	key, ok := dRepo.LookupSettingKey(c.args[0])
+	if !ok {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，不認識 %s，key 有 %s", c.args[0], settingKeyNames()))
+	}
+
+	if ok, err := isChatAdmin(ctx, c.tb, toID, chatID); err != nil || !ok {
+		if err != nil {
+			return err
+		}
+		return c.reply(ctx, chatID, "我是懶惰老鼠，只聽管理員的")
+	}
+
+	settings, err := c.settings.GetChatSettings(ctx, chatID)
+	if err != nil {
+		log.Println("get chat settings failed: ", err.Error())
+		return err
+	}
+
+	msg := ""
+	if strings.EqualFold(c.args[1], "default") {
+		delete(settings.Values, key)
+		msg = fmt.Sprintf("我是懶惰老鼠，%s 改回預設值", key)
+	} else {
+		v, ok := parseSettingValue(key, c.args[1])
+		if !ok {
+			return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，%s 不能是 %s", key, c.args[1]))
+		}
+		settings.Values[key] = v
+		msg = fmt.Sprintf("我是懶惰老鼠，%s 改成 %s", key, c.args[1])
+	}
+
+	settings.UpdatedBy, settings.UpdatedAt = toID, time.Now()
+	if err := c.settings.SaveChatSettings(ctx, settings); err != nil {
+		log.Println("save chat settings failed: ", err.Error())
+		return err
+	}
+	return c.reply(ctx, chatID, msg)
+}
+
+// isChatAdmin tells whether the user may change the chat, in a private chat the chat is the user
+func isChatAdmin(ctx context.Context, tb dRepo.TelegramBotRepo, userID int64, chatID int64) (bool, error) {
+	if chatID == userID {
+		return true, nil
+	}
+	member, err := tb.GetChatMember(ctx, dRepo.GetChatMemberRequest{ChatID: chatID, UserID: userID})
+	if err != nil {
+		log.Println("get chat member failed: ", err.Error())
+		return false, err
+	}
+	return member.IsAdmin(), nil
+}
+
+// alertChatID is the chat every alert goes to, whether or not it subscribed
+func alertChatID(cfg *config.TelegramCfg) int64 {
+	if config.IsDevelopment() {
+		return cfg.QuoteComparisonBot.TestGroupChatID
+	}
+	return cfg.QuoteComparisonBot.GroupChatID
+}
+
+func (c *setCommand) reply(ctx context.Context, chatID int64, msg string) error {
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+		ChatID: chatID,
+		Text:   msg,
+	})
+}
+
+func settingKeyNames() string {
+	names := make([]string, 0, len(dRepo.SettingKeys))
+	for _, v := range dRepo.SettingKeys {
+		names = append(names, string(v))
+	}
+	return strings.Join(names, ", ")
+}
+
+type settingsCommand struct {
+	cfg      *config.TelegramCfg
+	tb       dRepo.TelegramBotRepo
+	settings dRepo.SettingsRepo
+}
+
+func newSettingsCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, settings dRepo.SettingsRepo) commandHandler {
+	return &settingsCommand{cfg: cfg, tb: tb, settings: settings}
+}
+
+// Reply handles /settings, the values the chat alerts with.
+func (c *settingsCommand) Reply(toID int64, chatID int64) error {
+	ctx := context.Background()
+
+	settings, err := c.settings.GetChatSettings(ctx, chatID)
+	if err != nil {
+		log.Println("get chat settings failed: ", err.Error())
+		return err
+	}
+
+	cfg := chatConfig(c.cfg, settings)
+	values := make([]dRepo.ChatSetting, 0, len(dRepo.SettingKeys))
+	for _, key := range dRepo.SettingKeys {
+		_, ok := settings.Values[key]
+		values = append(values, dRepo.ChatSetting{Key: key, Value: settingOf(cfg, key), IsDefault: !ok})
+	}
+
+	return c.tb.SendSettingsNotify(ctx, dRepo.SendSettingsNotifyRequest{
+		ChatID:     chatID,
+		Settings:   values,
+		Subscribed: settings.Subscribed || chatID == alertChatID(c.cfg),
+	})
+}
+
+type subscribeCommand struct {
+	cfg       *config.TelegramCfg
+	tb        dRepo.TelegramBotRepo
+	settings  dRepo.SettingsRepo
+	subscribe bool
+}
+
+func newSubscribeCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, settings dRepo.SettingsRepo, subscribe bool) commandHandler {
+	return &subscribeCommand{cfg: cfg, tb: tb, settings: settings, subscribe: subscribe}
+}
+
+// Reply handles /subscribe and /unsubscribe, whether the arbitrage alerts come to the chat.
+// Only chat admins may change a group, the alert chat always gets them.
+func (c *subscribeCommand) Reply(toID int64, chatID int64) error {
+	ctx := context.Background()
+
+	if chatID == alertChatID(c.cfg) {
+		return c.reply(ctx, chatID, "我是懶惰老鼠，這裡本來就會收到通知")
+	}
+
+	if ok, err := isChatAdmin(ctx, c.tb, toID, chatID); err != nil || !ok {
+		if err != nil {
+			return err
+		}
+		return c.reply(ctx, chatID, "我是懶惰老鼠，只聽管理員的")
+	}
+
+	settings, err := c.settings.GetChatSettings(ctx, chatID)
+	if err != nil {
+		log.Println("get chat settings failed: ", err.Error())
+		return err
+	}
+
+	settings.Subscribed = c.subscribe
+	settings.UpdatedBy, settings.UpdatedAt = toID, time.Now()
+	if err := c.settings.SaveChatSettings(ctx, settings); err != nil {
+		log.Println("save chat settings failed: ", err.Error())
+		return err
+	}
+
+	if c.subscribe {
+		return c.reply(ctx, chatID, "我是懶惰老鼠，之後有套利會通知這裡，門檻看 /settings")
+	}
+	return c.reply(ctx, chatID, "我是懶惰老鼠，不再通知這裡了")
+}
+
+func (c *subscribeCommand) reply(ctx context.Context, chatID int64, msg string) error {
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+		ChatID: chatID,
+		Text:   msg,
+	})
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// slippageDepthLevels is how many levels of each book are walked for the invest size
+	slippageDepthLevels = 50
+	// maxInvestSearchSteps bounds the bisection for the largest size clearing MinArbitrage
+	maxInvestSearchSteps = 40
+)
+
+// orderBook is one side of a book, asks for the buy leg and bids for the sell leg.
+// An unbounded book has no depth data, e.g. an exchange without a depth api,
+// and fills any size at its only level, which is the top of book quote.
+type orderBook struct {
+	levels    []dRepo.DepthLevel
+	unbounded bool
+}
+
+func topOfBook(price decimal.Decimal) orderBook {
+	return orderBook{levels: []dRepo.DepthLevel{{Price: price}}, unbounded: true}
+}
+
+// loadOrderBooks fetches the asks of the buy exchange and the bids of the sell exchange,
+// a leg without depth falls back to its top of book quote
+func loadOrderBooks(ctx context.Context, depth dRepo.DepthRepo, pair constant.Pair, buyExchange constant.Exchange, buyPrice decimal.Decimal, sellExchange constant.Exchange, sellPrice decimal.Decimal) (asks orderBook, bids orderBook) {
+	asks, bids = topOfBook(buyPrice), topOfBook(sellPrice)
+	if depth == nil {
+		return asks, bids
+	}
+
+	if resp, err := getDepth(ctx, depth, buyExchange, pair); resp != nil && len(resp.Asks) > 0 {
+		asks = orderBook{levels: resp.Asks}
+	} else if err != nil {
+		log.Printf("get %s depth failed, using top of book: %s", buyExchange, err.Error())
+	}
+
+	if resp, err := getDepth(ctx, depth, sellExchange, pair); resp != nil && len(resp.Bids) > 0 {
+		bids = orderBook{levels: resp.Bids}
+	} else if err != nil {
+		log.Printf("get %s depth failed, using top of book: %s", sellExchange, err.Error())
+	}
+
+	return asks, bids
+}
+
+func getDepth(ctx context.Context, depth dRepo.DepthRepo, exchange constant.Exchange, pair constant.Pair) (*dRepo.GetDepthResponse, error) {
+	resp, err := depth.GetDepth(ctx, dRepo.GetDepthRequest{Exchange: exchange, Base: pair.Base, Quote: pair.Quote, Limit: slippageDepthLevels})
+	if errors.Is(err, dRepo.ErrDepthUnsupported) {
+		return nil, nil
+	}
+	return resp, err
+}
+
+func (b orderBook) top() decimal.Decimal {
+	if len(b.levels) == 0 {
+		return decimal.Zero
+	}
+	return b.levels[0].Price
+}
+
+// buy spends quote on the asks and returns the base bought, ok is false when the book runs out
+func (b orderBook) buy(quote decimal.Decimal) (base decimal.Decimal, ok bool) {
+	remaining := quote
+	for _, v := range b.levels {
+		if !v.Price.IsPositive() {
+			continue
+		}
+		cost := v.Price.Mul(v.Volume)
+		if b.unbounded || cost.GreaterThanOrEqual(remaining) {
+			return base.Add(remaining.Div(v.Price)), true
+		}
+		base = base.Add(v.Volume)
+		remaining = remaining.Sub(cost)
+	}
+	return base, false
+}
+
+// cost returns the quote needed to buy base from the asks, ok is false when the book runs out
+func (b orderBook) cost(base decimal.Decimal) (quote decimal.Decimal, ok bool) {
+	remaining := base
+	for _, v := range b.levels {
+		if b.unbounded || v.Volume.GreaterThanOrEqual(remaining) {
+			return quote.Add(remaining.Mul(v.Price)), true
+		}
+		quote = quote.Add(v.Price.Mul(v.Volume))
+		remaining = remaining.Sub(v.Volume)
+	}
+	return quote, false
+}
+
+// sell sells base into the bids and returns the quote received, ok is false when the book runs out
+func (b orderBook) sell(base decimal.Decimal) (quote decimal.Decimal, ok bool) {
+	remaining := base
+	for _, v := range b.levels {
+		if b.unbounded || v.Volume.GreaterThanOrEqual(remaining) {
+			return quote.Add(remaining.Mul(v.Price)), true
+		}
+		quote = quote.Add(v.Price.Mul(v.Volume))
+		remaining = remaining.Sub(v.Volume)
+	}
+	return quote, false
+}
+
+func (b orderBook) volume() decimal.Decimal {
+	volume := decimal.Zero
+	for _, v := range b.levels {
+		volume = volume.Add(v.Volume)
+	}
+	return volume
+}
+
+// capacity is the largest quote amount both books can fill, nil when neither book limits it
+func capacity(asks, bids orderBook) *decimal.Decimal {
+	var limit *decimal.Decimal
+	if !asks.unbounded {
+		quote, _ := asks.cost(asks.volume())
+		limit = &quote
+	}
+	if !bids.unbounded {
+		// buying more base than the bids take is pointless
+		quote, ok := asks.cost(bids.volume())
+		if ok && (limit == nil || quote.LessThan(*limit)) {
+			limit = &quote
+		}
+	}
+	return limit
+}
+
+type fill struct {
+	Invest    decimal.Decimal
+	Volume    decimal.Decimal
+	Return    decimal.Decimal
+	BuyPrice  decimal.Decimal
+	SellPrice decimal.Decimal
+	Arbitrage decimal.Decimal
+
+	BuyFees, SellFees dRepo.LegFees
+	NetProfit         decimal.Decimal
+	NetArbitrage      decimal.Decimal
+}
+
+// walk buys with invest quote on the asks and sells the base into the bids
+func walk(invest decimal.Decimal, asks, bids orderBook, fees tradeFees) (fill, bool) {
+	if !invest.IsPositive() {
+		return fill{}, false
+	}
+	base, ok := asks.buy(invest)
+	if !ok || !base.IsPositive() {
+		return fill{}, false
+	}
+	quote, ok := bids.sell(base)
+	if !ok {
+		return fill{}, false
+	}
+
+	f := fill{
+		Invest:    invest,
+		Volume:    base,
+		Return:    quote,
+		BuyPrice:  invest.Div(base),
+		SellPrice: quote.Div(base),
+		Arbitrage: quote.Sub(invest).Div(invest),
+	}
+	f.BuyFees, f.SellFees = fees.of(f)
+	f.NetProfit = quote.Sub(invest).Sub(f.BuyFees.Total()).Sub(f.SellFees.Total())
+	f.NetArbitrage = f.NetProfit.Div(invest)
+	return f, true
+}
+
+var maxInvestProbes = 20
+
+// maxInvest returns the largest quote amount whose fill still clears minArbitrage net,
+// nil when any size does. Flat fees make small sizes unprofitable and slippage large
+// ones, so the sizes clearing it form a range: halving the capacity of the books finds
+// a size inside it and the bisection up to the next larger probe finds its upper end.
+func maxInvest(minArbitrage decimal.Decimal, asks, bids orderBook, fees tradeFees) *decimal.Decimal {
+	zero := decimal.Zero
+	clears := func(invest decimal.Decimal) bool {
+		f, ok := walk(invest, asks, bids, fees)
+		return ok && f.NetArbitrage.GreaterThanOrEqual(minArbitrage)
+	}
+
+	top := asks.top()
+	if !top.IsPositive() {
+		return &zero
+	}
+
+	limit := capacity(asks, bids)
+	if limit == nil {
+		// flat fees vanish as the size grows, only the rates are left
+		rates := bids.top().Sub(top).Div(top).Sub(fees.Buy.TakerFee).Sub(fees.Sell.TakerFee)
+		if rates.GreaterThan(minArbitrage) {
+			return nil
+		}
+		return &zero
+	}
+
+	hi := *limit
+	if clears(hi) {
+		hi = hi.Truncate(0)
+		return &hi
+	}
+
+	lo := hi
+	for i := 0; ; i++ {
+		if i == maxInvestProbes {
+			return &zero
+		}
+		hi, lo = lo, lo.Div(decimal.NewFromInt(2))
+		if clears(lo) {
+			break
+		}
+	}
+
+	for i := 0; i < maxInvestSearchSteps; i++ {
+		mid := lo.Add(hi).Div(decimal.NewFromInt(2))
+		if clears(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	lo = lo.Truncate(0)
+	return &lo
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// book builds one side of a book from "price:volume" levels
+func book(levels ...string) orderBook {
+	b := orderBook{}
+	for _, v := range levels {
+		parts := strings.Split(v, ":")
+		b.levels = append(b.levels, dRepo.DepthLevel{Price: decimal.RequireFromString(parts[0]), Volume: decimal.RequireFromString(parts[1])})
+	}
+	return b
+}
+
+func dec(v string) decimal.Decimal {
+	return decimal.RequireFromString(v)
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		name string
+		asks orderBook
+		bids orderBook
+		want string
+	}{
+		{"no depth on either side", topOfBook(dec("31")), topOfBook(dec("31.5")), ""},
+		{"asks limit", book("31:100", "31.1:100"), topOfBook(dec("31.5")), "6210"},
+		{"bids limit", book("31:100", "31.1:100"), book("31.5:50"), "1550"},
+		{"bids deeper than asks", book("31:100", "31.1:100"), book("31.5:1000"), "6210"},
+		{"bids limit without ask depth", topOfBook(dec("31")), book("31.5:50", "31.4:50"), "3100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capacity(tt.asks, tt.bids)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("want no limit, got %s", got)
+				}
+				return
+			}
+			if got == nil || !got.Equal(dec(tt.want)) {
+				t.Fatalf("want %s, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	usdt := tradeFees{Asset: constant.USDT, Quote: constant.USDT}
+	taker := usdt
+	taker.Buy.TakerFee, taker.Sell.TakerFee = dec("0.001"), dec("0.001")
+
+	tests := []struct {
+		name      string
+		invest    string
+		asks      orderBook
+		bids      orderBook
+		fees      tradeFees
+		ok        bool
+		volume    string
+		buyPrice  string
+		netProfit string
+	}{
+		{name: "nothing to invest", invest: "0", asks: book("31:100"), bids: book("31.5:100"), fees: usdt},
+		{name: "asks thinner than the investment", invest: "5000", asks: book("31:100"), bids: book("31.5:1000"), fees: usdt},
+		{name: "bids thinner than the base bought", invest: "3100", asks: book("31:1000"), bids: book("31.5:50"), fees: usdt},
+		{
+			name: "fills across levels", invest: "6210", asks: book("31:100", "31.1:100"), bids: book("31.5:150", "31.4:100"), fees: usdt,
+			ok: true, volume: "200", buyPrice: "31.05", netProfit: "85",
+		},
+		{
+			name: "taker fees on both legs", invest: "6210", asks: book("31:100", "31.1:100"), bids: book("31.5:150", "31.4:100"), fees: taker,
+			ok: true, volume: "200", buyPrice: "31.05", netProfit: "72.495",
+		},
+		{
+			name: "top of book fills any size", invest: "3100000", asks: topOfBook(dec("31")), bids: topOfBook(dec("31.31")), fees: usdt,
+			ok: true, volume: "100000", buyPrice: "31", netProfit: "31000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := walk(dec(tt.invest), tt.asks, tt.bids, tt.fees)
+			if ok != tt.ok {
+				t.Fatalf("want ok %v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if !f.Volume.Equal(dec(tt.volume)) || !f.BuyPrice.Equal(dec(tt.buyPrice)) || !f.NetProfit.Equal(dec(tt.netProfit)) {
+				t.Errorf("want volume %s at %s for %s net, got %s at %s for %s", tt.volume, tt.buyPrice, tt.netProfit, f.Volume, f.BuyPrice, f.NetProfit)
+			}
+		})
+	}
+}
+
+func TestMaxInvest(t *testing.T) {
+	usdt := tradeFees{Asset: constant.USDT, Quote: constant.USDT}
+	flat := tradeFees{Asset: constant.USDT, Quote: constant.TWD, Buy: config.FeeSchedule{TWDDepositFee: dec("15")}}
+
+	tests := []struct {
+		name         string
+		minArbitrage string
+		asks         orderBook
+		bids         orderBook
+		fees         tradeFees
+		// want is empty when any size clears
+		want string
+	}{
+		{"no asks", "0.01", book(), book("31:100"), usdt, "0"},
+		{"first level below minArbitrage", "0.001", book("31:100", "30:100"), book("31:100"), usdt, "0"},
+		{"any size clears without depth", "0.01", topOfBook(dec("30")), topOfBook(dec("31")), usdt, ""},
+		{"no size clears without depth", "0.05", topOfBook(dec("30")), topOfBook(dec("31")), usdt, "0"},
+		{"whole capacity clears", "0.01", book("30:100"), book("31:100"), usdt, "3000"},
+		// the second ask level drags the fill below 1% at about 4309.86
+		{"slippage caps the size", "0.01", book("30:100", "31:100"), topOfBook(dec("30.6")), usdt, "4309"},
+		// a 15 TWD deposit fee takes another 15 off the net profit, about 3654.9 is left
+		{"flat fee shrinks the size", "0.01", book("30:100", "31:100"), topOfBook(dec("30.6")), flat, "3654"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maxInvest(dec(tt.minArbitrage), tt.asks, tt.bids, tt.fees)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("want no limit, got %s", got)
+				}
+				return
+			}
+			if got == nil || !got.Equal(dec(tt.want)) {
+				t.Fatalf("want %s, got %v", tt.want, got)
+			}
+			if !got.IsPositive() {
+				return
+			}
+
+			// the bisection converged onto the edge of the sizes clearing minArbitrage
+			clears := func(invest decimal.Decimal) bool {
+				f, ok := walk(invest, tt.asks, tt.bids, tt.fees)
+				return ok && f.NetArbitrage.GreaterThanOrEqual(dec(tt.minArbitrage))
+			}
+			if !clears(*got) {
+				t.Errorf("%s does not clear %s", got, tt.minArbitrage)
+			}
+			if limit := capacity(tt.asks, tt.bids); (limit == nil || got.LessThan(*limit)) && clears(got.Add(decimal.NewFromInt(1))) {
+				t.Errorf("%s still clears %s", got.Add(decimal.NewFromInt(1)), tt.minArbitrage)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+)
+
+// staleTracker remembers which quotes are stale so that each change is alerted once
+type staleTracker struct {
+	lock  sync.Mutex
+	stale map[dRepo.QuoteKey]bool
+}
+
+func newStaleTracker() *staleTracker {
+	return &staleTracker{stale: make(map[dRepo.QuoteKey]bool)}
+}
+
+// update stores the latest state and returns the quotes that turned stale and recovered
+func (t *staleTracker) update(stale map[dRepo.QuoteKey]bool) (turnedStale []dRepo.QuoteKey, recovered []dRepo.QuoteKey) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key, isStale := range stale {
+		if isStale == t.stale[key] {
+			continue
+		}
+		if isStale {
+			turnedStale = append(turnedStale, key)
+		} else {
+			recovered = append(recovered, key)
+		}
+		t.stale[key] = isStale
+	}
+
+	sort.Slice(turnedStale, func(i, j int) bool { return turnedStale[i].String() < turnedStale[j].String() })
+	sort.Slice(recovered, func(i, j int) bool { return recovered[i].String() < recovered[j].String() })
+	return turnedStale, recovered
+}
+
+func isStaleQuote(cfg *config.TelegramCfg, exchange constant.Exchange, info dRepo.QuotationInfo) bool {
+	maxAge := cfg.QuoteComparisonBot.StaleQuoteAgeOf(exchange)
+	return maxAge > 0 && info.Age() > maxAge
+}
+
+// checkStaleQuotes alerts the admin about quotes that went stale or recovered
+// and returns the stale ones, both those in Infos and those a source rejected as stale
+func (u *telegramUseCase) checkStaleQuotes(ctx context.Context, qInfo *dRepo.GetQuotationsResponse) map[dRepo.QuoteKey]bool {
+	stale := make(map[dRepo.QuoteKey]bool, len(qInfo.Infos)+len(qInfo.Rejected))
+	for key, info := range qInfo.Infos {
+		stale[key] = isStaleQuote(u.cfg, key.Exchange, info)
+	}
+	for key, err := range qInfo.Rejected {
+		if errors.Is(err, dRepo.ErrStaleQuote) {
+			stale[key] = true
+		}
+	}
+
+	turnedStale, recovered := u.stale.update(stale)
+
+	for _, v := range turnedStale {
+		info, ok := qInfo.Infos[v]
+		if !ok {
+			u.notifyError(ctx, "Stale Quote", qInfo.Rejected[v].Error())
+			continue
+		}
+		u.notifyError(ctx, "Stale Quote", fmt.Sprintf("%s (%s) has not updated for %s", v, info.Source, info.Age().Truncate(time.Second)))
+	}
+	for _, v := range recovered {
+		u.notifyError(ctx, "Quote Recovered", fmt.Sprintf("%s (%s) is updating again", v, qInfo.Infos[v].Source))
+	}
+
+	return stale
+}
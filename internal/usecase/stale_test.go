@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// errorNotifies records the admin notifies, every other call panics
+type errorNotifies struct {
+	dRepo.TelegramBotRepo
+	sent []dRepo.SendErrorNotifyRequest
+}
+
+func (n *errorNotifies) SendErrorNotify(ctx context.Context, req dRepo.SendErrorNotifyRequest) error {
+	n.sent = append(n.sent, req)
+	return nil
+}
+
+func TestCheckStaleQuotesSeesRejectedQuotes(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123456:TEST-token_abc")
+	cfg := config.NewConfig(true)
+	tb := &errorNotifies{}
+	u := &telegramUseCase{cfg: cfg.Telegram, tb: tb, stale: newStaleTracker()}
+
+	key := dRepo.QuoteKey{Exchange: constant.BitoPro, Pair: constant.Pair{Base: constant.ETH, Quote: constant.TWD}}
+	info := dRepo.QuotationInfo{
+		BuyPrice:   decimal.NewFromInt(100000),
+		SellPrice:  decimal.NewFromInt(99900),
+		UpdateTime: time.Now().Add(-time.Hour),
+		Source:     string(constant.BitoPro),
+	}
+
+	// the adapter rejected the frozen quote itself
+	stale := u.checkStaleQuotes(context.Background(), dRepo.NewQuoteResponse(key, info, cfg.Exchange.StaleQuoteAgeOf(key.Exchange)))
+	if !stale[key] || len(tb.sent) != 1 || tb.sent[0].Title != "Stale Quote" || !strings.Contains(tb.sent[0].ErrMsg, "updated 1h0m0s ago") {
+		t.Fatalf("want one stale alert, got %v %+v", stale, tb.sent)
+	}
+
+	// still stale, no second alert
+	u.checkStaleQuotes(context.Background(), dRepo.NewQuoteResponse(key, info, cfg.Exchange.StaleQuoteAgeOf(key.Exchange)))
+	if len(tb.sent) != 1 {
+		t.Fatalf("want the stale quote alerted once, got %+v", tb.sent)
+	}
+
+	info.UpdateTime = time.Now()
+	stale = u.checkStaleQuotes(context.Background(), dRepo.NewQuoteResponse(key, info, cfg.Exchange.StaleQuoteAgeOf(key.Exchange)))
+	if stale[key] || len(tb.sent) != 2 || tb.sent[1].Title != "Quote Recovered" {
+		t.Errorf("want a recovered alert, got %v %+v", stale, tb.sent)
+	}
+}
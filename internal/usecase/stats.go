@@ -0,0 +1,284 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	defaultStatsWindow = 24 * time.Hour
+	maxStatsWindow     = 365 * 24 * time.Hour
+	// statsSampleGap caps how long one sample counts towards an opportunity,
+	// so that a gap in the history, e.g. a restart, is not counted as one
+	statsSampleGap = 5 * time.Minute
+)
+
+// routeSample is the spread of a route at one recorded time
+type routeSample struct {
+	Time      time.Time
+	Spread    decimal.Decimal
+	Arbitrage decimal.Decimal
+}
+
+// routeSamples joins the close prices of both legs at the times both have a
+// point. Both legs are recorded from the same snapshots and downsampled to the
+// same buckets, so their times line up.
+func routeSamples(buys, sells []dRepo.HistoryPoint) []routeSample {
+	buyAt := make(map[int64]dRepo.HistoryPoint, len(buys))
+	for _, v := range buys {
+		buyAt[v.Time.UnixNano()] = v
+	}
+
+	samples := []routeSample{}
+	for _, v := range sells {
+		buy, ok := buyAt[v.Time.UnixNano()]
+		if !ok || !buy.Buy.Close.IsPositive() {
+			continue
+		}
+		spread := v.Sell.Close.Sub(buy.Buy.Close)
+		samples = append(samples, routeSample{Time: v.Time, Spread: spread, Arbitrage: spread.Div(buy.Buy.Close)})
+	}
+	return samples
+}
+
+// statsResolution keeps long windows to a manageable number of points
+func statsResolution(window time.Duration) dRepo.Resolution {
+	switch {
+	case window <= 24*time.Hour:
+		return dRepo.ResolutionRaw
+	case window <= 30*24*time.Hour:
+		return dRepo.ResolutionMinute
+	default:
+		return dRepo.ResolutionHour
+	}
+}
+
+// pairStats summarizes every route between the exchanges recorded for the pair over [from, to],
+// the topN with the most time above minArbitrage come first. Without any samples the
+// watched route is returned empty so that the reply still names the pair.
+func pairStats(ctx context.Context, history dRepo.HistoryRepo, route *config.PairCfg, from time.Time, to time.Time, minArbitrage decimal.Decimal, topN int) ([]dRepo.RouteStats, error) {
+	resolution := statsResolution(to.Sub(from))
+	resp, err := history.GetHistory(ctx, dRepo.GetHistoryRequest{
+		Base:       route.Pair.Base,
+		Quote:      route.Pair.Quote,
+		From:       from,
+		To:         to,
+		Resolution: resolution,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// points are sorted by exchange, so are the exchanges
+	exchanges := []constant.Exchange{}
+	for _, v := range resp.Points {
+		if len(exchanges) == 0 || exchanges[len(exchanges)-1] != v.Exchange {
+			exchanges = append(exchanges, v.Exchange)
+		}
+	}
+
+	stats := []dRepo.RouteStats{}
+	for _, buy := range exchanges {
+		for _, sell := range exchanges {
+			if buy == sell {
+				continue
+			}
+			s := routeStats(route.Pair, buy, sell, resp, resolution, to, minArbitrage)
+			if s.Samples > 0 {
+				stats = append(stats, s)
+			}
+		}
+	}
+	if len(stats) == 0 {
+		return []dRepo.RouteStats{{Pair: route.Pair, ExchangeBuy: route.ExchangeBuy, ExchangeSell: route.ExchangeSell}}, nil
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].OpportunityTime != stats[j].OpportunityTime {
+			return stats[i].OpportunityTime > stats[j].OpportunityTime
+		}
+		return stats[i].Arbitrage.Mean.GreaterThan(stats[j].Arbitrage.Mean)
+	})
+	if topN > 0 && len(stats) > topN {
+		stats = stats[:topN]
+	}
+	return stats, nil
+}
+
+// routeStats summarizes buying on buy and selling on sell from the recorded points of the pair
+func routeStats(pair constant.Pair, buy constant.Exchange, sell constant.Exchange, resp *dRepo.GetHistoryResponse, resolution dRepo.Resolution, to time.Time, minArbitrage decimal.Decimal) dRepo.RouteStats {
+	stats := dRepo.RouteStats{Pair: pair, ExchangeBuy: buy, ExchangeSell: sell}
+
+	samples := routeSamples(resp.Series(buy), resp.Series(sell))
+	stats.Samples = len(samples)
+	if len(samples) == 0 {
+		return stats
+	}
+
+	spreads := make([]decimal.Decimal, 0, len(samples))
+	arbitrages := make([]decimal.Decimal, 0, len(samples))
+	for _, v := range samples {
+		spreads = append(spreads, v.Spread)
+		arbitrages = append(arbitrages, v.Arbitrage)
+	}
+	stats.Spread = summarize(spreads)
+	stats.Arbitrage = summarize(arbitrages)
+
+	gap := statsSampleGap
+	if d := resolution.Duration(); d > gap {
+		gap = d
+	}
+	stats.Opportunities, stats.OpportunityTime = opportunities(samples, to, minArbitrage, gap)
+	return stats
+}
+
+// summarize returns the order statistics of values, which must not be empty
+func summarize(values []decimal.Decimal) dRepo.SummaryStats {
+	sorted := append([]decimal.Decimal{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+
+	return dRepo.SummaryStats{
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   decimal.Sum(sorted[0], sorted[1:]...).Div(decimal.NewFromInt(int64(len(sorted)))),
+		Median: percentile(sorted, decimal.NewFromFloat(0.5)),
+		P95:    percentile(sorted, decimal.NewFromFloat(0.95)),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of sorted
+func percentile(sorted []decimal.Decimal, p decimal.Decimal) decimal.Decimal {
+	rank := p.Mul(decimal.NewFromInt(int64(len(sorted) - 1)))
+	lower := rank.IntPart()
+	if int(lower) >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	weight := rank.Sub(decimal.NewFromInt(lower))
+	return sorted[lower].Add(sorted[lower+1].Sub(sorted[lower]).Mul(weight))
+}
+
+// opportunities counts the runs of samples at or above minArbitrage and their total
+// length. A sample lasts until the next one, at most gap, the last one until end.
+func opportunities(samples []routeSample, end time.Time, minArbitrage decimal.Decimal, gap time.Duration) (count int, total time.Duration) {
+	above := false
+	for i, v := range samples {
+		if v.Arbitrage.LessThan(minArbitrage) {
+			above = false
+			continue
+		}
+		if !above {
+			count++
+			above = true
+		}
+
+		next := end
+		if i+1 < len(samples) {
+			next = samples[i+1].Time
+		}
+		d := next.Sub(v.Time)
+		if d > gap {
+			d = gap
+		}
+		if d > 0 {
+			total += d
+		}
+	}
+	return count, total
+}
+
+// parseWindow accepts go durations and whole days, e.g. 90m, 24h or 7d
+func parseWindow(s string) (time.Duration, bool) {
+	if days := strings.TrimSuffix(strings.ToLower(s), "d"); days != strings.ToLower(s) {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+type statsCommand struct {
+	cfg     *config.TelegramCfg
+	tb      dRepo.TelegramBotRepo
+	history dRepo.HistoryRepo
+	args    []string
+}
+
+func newStatsCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, history dRepo.HistoryRepo, args []string) commandHandler {
+	return &statsCommand{cfg: cfg, tb: tb, history: history, args: args}
+}
+
+// Reply handles /stats [window] [pair], e.g. /stats 7d or /stats 24h USDC, without a pair
+// every watched pair is summarized. Each pair lists its best routes between the recorded
+// exchanges, not only the watched one.
+func (c *statsCommand) Reply(toID int64, chatID int64) error {
+	ctx := context.Background()
+
+	window := defaultStatsWindow
+	routes := c.cfg.QuoteComparisonBot.Pairs
+	for _, arg := range c.args {
+		if d, ok := parseWindow(arg); ok {
+			window = d
+			continue
+		}
+		p, ok := constant.LookupPair(arg)
+		if !ok {
+			return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，不認識 %s", arg))
+		}
+		route, ok := c.cfg.QuoteComparisonBot.PairCfgOf(p)
+		if !ok {
+			return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，沒有在看 %s", p))
+		}
+		routes = []*config.PairCfg{route}
+	}
+
+	if window > maxStatsWindow {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，最多只看 %d 天", maxStatsWindow/(24*time.Hour)))
+	}
+
+	to := time.Now()
+	from := to.Add(-window)
+	minArbitrage := c.cfg.QuoteComparisonBot.MinArbitrage
+	topN := c.cfg.QuoteComparisonBot.ArbitrageTopN
+
+	stats := []dRepo.RouteStats{}
+	for _, v := range routes {
+		s, err := pairStats(ctx, c.history, v, from, to, minArbitrage, topN)
+		if err != nil {
+			log.Println("get route stats failed: ", v.Pair, err.Error())
+			return err
+		}
+		stats = append(stats, s...)
+	}
+
+	return c.tb.SendStatsNotify(ctx, dRepo.SendStatsNotifyRequest{
+		ChatID:       chatID,
+		Window:       window,
+		MinArbitrage: minArbitrage,
+		TopN:         topN,
+		Routes:       stats,
+	})
+}
+
+func (c *statsCommand) reply(ctx context.Context, chatID int64, msg string) error {
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+		ChatID: chatID,
+		Text:   msg,
+	})
+}
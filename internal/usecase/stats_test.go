@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gummy789j/telegram-quote-bot/internal/config"
+	"github.com/gummy789j/telegram-quote-bot/internal/constant"
+	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
+	"github.com/shopspring/decimal"
+)
+
+// fixedHistory answers every GetHistory with points
+type fixedHistory struct {
+	dRepo.HistoryRepo
+	points []dRepo.HistoryPoint
+}
+
+func (h *fixedHistory) GetHistory(ctx context.Context, req dRepo.GetHistoryRequest) (*dRepo.GetHistoryResponse, error) {
+	return &dRepo.GetHistoryResponse{Points: h.points}, nil
+}
+
+func TestPairStatsCoversEveryRecordedRoute(t *testing.T) {
+	to := time.Now().Truncate(time.Minute)
+	from := to.Add(-time.Hour)
+	quotes := map[constant.Exchange][2]string{
+		// buy at, sell at
+		constant.ACE:     {"32.30", "32.20"},
+		constant.MAX:     {"32.45", "32.41"},
+		constant.BitoPro: {"32.50", "32.44"},
+	}
+
+	points := []dRepo.HistoryPoint{}
+	for _, exchange := range []constant.Exchange{constant.ACE, constant.BitoPro, constant.MAX} {
+		for i := 0; i < 3; i++ {
+			points = append(points, dRepo.HistoryPoint{
+				Exchange: exchange,
+				Pair:     constant.USDTTWD,
+				Time:     from.Add(time.Duration(i) * time.Minute),
+				Buy:      dRepo.NewOHLC(decimal.RequireFromString(quotes[exchange][0])),
+				Sell:     dRepo.NewOHLC(decimal.RequireFromString(quotes[exchange][1])),
+				Count:    1,
+			})
+		}
+	}
+
+	// the watched route is not the best one
+	route := &config.PairCfg{Pair: constant.USDTTWD, ExchangeBuy: constant.MAX, ExchangeSell: constant.BitoPro}
+	minArbitrage := decimal.RequireFromString("0.003")
+
+	stats, err := pairStats(context.Background(), &fixedHistory{points: points}, route, from, to, minArbitrage, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 6 {
+		t.Fatalf("want every route of 3 exchanges, got %d", len(stats))
+	}
+	if best := stats[0]; best.ExchangeBuy != constant.ACE || best.ExchangeSell != constant.BitoPro || best.Opportunities != 1 {
+		t.Errorf("want ACE>BitoPro ranked first with one opportunity, got %+v", best)
+	}
+
+	top, err := pairStats(context.Background(), &fixedHistory{points: points}, route, from, to, minArbitrage, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].ExchangeBuy != constant.ACE || top[1].ExchangeSell != constant.MAX {
+		t.Errorf("want the best 2 routes, got %+v", top)
+	}
+
+	empty, err := pairStats(context.Background(), &fixedHistory{}, route, from, to, minArbitrage, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 1 || empty[0].ExchangeBuy != constant.MAX || empty[0].Samples != 0 {
+		t.Errorf("want the watched route without samples, got %+v", empty)
+	}
+}
@@ -2,21 +2,29 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 
 	"github.com/gummy789j/telegram-quote-bot/internal/config"
 	"github.com/gummy789j/telegram-quote-bot/internal/constant"
 	dRepo "github.com/gummy789j/telegram-quote-bot/internal/domain/repo"
 	dUc "github.com/gummy789j/telegram-quote-bot/internal/domain/usecase"
+	"github.com/gummy789j/telegram-quote-bot/internal/transport"
 	"github.com/shopspring/decimal"
 )
 
 type telegramUseCase struct {
-	cfg   *config.TelegramCfg
-	tb    dRepo.TelegramBotRepo
-	quote dRepo.QuoteRepo
+	cfg      *config.TelegramCfg
+	tb       dRepo.TelegramBotRepo
+	quote    dRepo.QuoteRepo
+	depth    dRepo.DepthRepo
+	history  dRepo.HistoryRepo
+	settings dRepo.SettingsRepo
+	stale    *staleTracker
+	alert    *alertCooldown
 
 	// mutex
 	lock *sync.Mutex
@@ -26,8 +34,8 @@ var _ dUc.TelegramUseCase = (*telegramUseCase)(nil)
 
 var latestUpdateID int64
 
-func NewTelegramUseCase(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, quote dRepo.QuoteRepo) dUc.TelegramUseCase {
-	uc := &telegramUseCase{cfg: cfg, tb: tb, quote: quote, lock: &sync.Mutex{}}
+func NewTelegramUseCase(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, quote dRepo.QuoteRepo, depth dRepo.DepthRepo, history dRepo.HistoryRepo, settings dRepo.SettingsRepo) dUc.TelegramUseCase {
+	uc := &telegramUseCase{cfg: cfg, tb: tb, quote: quote, depth: depth, history: history, settings: settings, stale: newStaleTracker(), alert: newAlertCooldown(), lock: &sync.Mutex{}}
 
 	// get the latest update id and store it
 	umResp, err := uc.tb.GetUpdates(context.Background(), dRepo.GetUpdatesRequest{})
@@ -53,7 +61,7 @@ func (u *telegramUseCase) ReplyCommand(ctx context.Context, req dUc.ReplyCommand
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic: %v", r)
 		}
-		if err != nil {
+		if err != nil && !errors.Is(err, transport.ErrCircuitOpen) {
 			u.notifyError(ctx, "ReplyCommand", err.Error())
 		}
 	}()
@@ -80,11 +88,16 @@ func (u *telegramUseCase) ReplyCommand(ctx context.Context, req dUc.ReplyCommand
 			continue
 		}
 
+		// commands answer with the thresholds of the chat they came from
 		if err := newCommandFactory(commandFactoryReq{
-			cfg:         u.cfg,
+			cfg:         u.configOf(ctx, v.FromChatID),
 			commandType: v.Command,
 			tb:          u.tb,
 			quote:       u.quote,
+			depth:       u.depth,
+			history:     u.history,
+			settings:    u.settings,
+			args:        v.Args,
 		}).Reply(v.FromID, v.FromChatID); err != nil {
 			log.Println("reply command failed: ", err.Error())
 			return err
@@ -105,72 +118,188 @@ func (u *telegramUseCase) NotifyArbitrage(ctx context.Context, req dUc.NotifyArb
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic: %v", r)
 		}
-		if err != nil {
+		if err != nil && !errors.Is(err, transport.ErrCircuitOpen) {
 			u.notifyError(ctx, "NotifyArbitrage", err.Error())
 		}
 	}()
 
 	// get comparison quote
-	qInfo, err := u.quote.GetQuotations(ctx, dRepo.GetQuotationsRequest{})
+	qInfo, err := u.quote.GetQuotations(ctx, dRepo.NewGetQuotationsRequest(req.Pair))
 	if err != nil {
 		log.Println("get quotations failed: ", err.Error())
 		return err
 	}
 
-	// calculate arbitrage info
-	aInfo := calArbitrageInfo(u.cfg.QuoteComparisonBot.DefaultInvest, qInfo.Infos[req.ExchangeBuy].BuyPrice, qInfo.Infos[req.ExchangeSell].SellPrice)
+	// stale quotes are not worth an alert
+	stale := u.checkStaleQuotes(ctx, qInfo)
 
-	if aInfo.Arbitrage.LessThan(u.cfg.QuoteComparisonBot.MinArbitrage) &&
-		aInfo.Spread.LessThan(u.cfg.QuoteComparisonBot.MinSpread) {
-		return nil
+	// every chat alerts with its own thresholds, one chat failing does not keep the others waiting
+	for _, chat := range u.alertChats(ctx, req.ToChatID) {
+		if chatErr := u.notifyChatArbitrage(ctx, req, chat, qInfo, stale); chatErr != nil {
+			log.Println("notify arbitrage failed: ", chat.ChatID, chatErr.Error())
+			err = chatErr
+		}
+	}
+	if err != nil {
+		return err
 	}
 
-	var isExcitedArbitrage, isExcitedSpread bool
+	// the other routes still alert, the admin hears why the left out quotes are missing,
+	// stale ones already got their own alert
+	problems := []error{}
+	for _, v := range quoteProblems(u.cfg, req.Pair, qInfo, nil) {
+		if !errors.Is(v, dRepo.ErrStaleQuote) {
+			problems = append(problems, v)
+		}
+	}
+	if len(problems) > 0 {
+		err = errors.New(joinErrors(problems))
+	}
+	return err
+}
 
-	if aInfo.Arbitrage.GreaterThanOrEqual(u.cfg.QuoteComparisonBot.ExcitedArbitrage) {
-		isExcitedArbitrage = true
+// alertChats is the alert chat followed by every chat that ran /subscribe, each with its
+// settings, the alert chat alone when the settings cannot be listed
+func (u *telegramUseCase) alertChats(ctx context.Context, alertChatID int64) []*dRepo.ChatSettings {
+	stored, err := u.settings.ListChatSettings(ctx)
+	if err != nil {
+		log.Println("list chat settings failed: ", err.Error())
+	}
+
+	chats := []*dRepo.ChatSettings{{ChatID: alertChatID}}
+	for _, v := range stored {
+		switch {
+		case v.ChatID == alertChatID:
+			chats[0] = v
+		case v.Subscribed:
+			chats = append(chats, v)
+		}
 	}
+	return chats
+}
 
-	if aInfo.Spread.GreaterThanOrEqual(u.cfg.QuoteComparisonBot.ExcitedSpread) {
-		isExcitedSpread = true
+func (u *telegramUseCase) notifyChatArbitrage(ctx context.Context, req dUc.NotifyArbitrageRequest, chat *dRepo.ChatSettings, qInfo *dRepo.GetQuotationsResponse, stale map[dRepo.QuoteKey]bool) error {
+	cfg := chatConfig(u.cfg, chat)
+	minArbitrage, minSpread := cfg.QuoteComparisonBot.MinArbitrage, cfg.QuoteComparisonBot.MinSpreadOf(req.Pair)
+	alertKey := func(r scannedRoute) string {
+		return fmt.Sprintf("%d %s %s>%s", chat.ChatID, req.Pair, r.Buy, r.Sell)
 	}
 
-	// send arbitrage notify
-	err = u.tb.SendArbitrageNotify(ctx, dRepo.SendArbitrageNotifyRequest{
-		ChatID:             req.ToChatID,
-		InvestAmount:       u.cfg.QuoteComparisonBot.DefaultInvest,
-		ExchangeBuy:        req.ExchangeBuy,
-		ExchangeSell:       req.ExchangeSell,
-		BuyPrice:           qInfo.Infos[req.ExchangeBuy].BuyPrice,
-		SellPrice:          qInfo.Infos[req.ExchangeSell].SellPrice,
+	// fees and slippage only take from the top of book figures, skip the order books when those miss already
+	routes := scanRoutes(ctx, cfg, u.depth, req.Pair, qInfo, stale, func(r scannedRoute) bool {
+		if r.Arbitrage.Arbitrage.LessThan(minArbitrage) && r.Arbitrage.Spread.LessThan(minSpread) {
+			return false
+		}
+		return u.alert.ready(alertKey(r), req.Cooldown)
+	}, 0)
+
+	for _, r := range routes {
+		// only what is left after the fees is worth an alert
+		if r.Arbitrage.NetArbitrage.LessThan(minArbitrage) && r.Arbitrage.NetSpread.LessThan(minSpread) {
+			continue
+		}
+
+		// send arbitrage notify
+		if err := u.tb.SendArbitrageNotify(ctx, arbitrageNotifyRequest(cfg, chat.ChatID, qInfo, r)); err != nil {
+			log.Println("send arbitrage notify failed: ", err.Error())
+			return err
+		}
+		u.alert.mark(alertKey(r))
+	}
+	return nil
+}
+
+// arbitrageNotifyRequest is the alert of a scanned route
+func arbitrageNotifyRequest(cfg *config.TelegramCfg, chatID int64, qInfo *dRepo.GetQuotationsResponse, r scannedRoute) dRepo.SendArbitrageNotifyRequest {
+	aInfo := r.Arbitrage
+	return dRepo.SendArbitrageNotifyRequest{
+		ChatID:             chatID,
+		Pair:               r.Pair,
+		InvestAmount:       aInfo.Invest,
+		ExchangeBuy:        r.Buy,
+		ExchangeSell:       r.Sell,
+		BuyPrice:           r.BuyInfo.BuyPrice,
+		SellPrice:          r.SellInfo.SellPrice,
 		Spread:             aInfo.Spread,
 		Arbitrage:          aInfo.Arbitrage,
 		Profit:             aInfo.Profit,
-		IsExcitedArbitrage: isExcitedArbitrage,
-		IsExcitedSpread:    isExcitedSpread,
-	})
-	if err != nil {
-		log.Println("send arbitrage notify failed: ", err.Error())
-		return err
+		VWAPBuyPrice:       aInfo.VWAPBuyPrice,
+		VWAPSellPrice:      aInfo.VWAPSellPrice,
+		EffectiveArbitrage: aInfo.EffectiveArbitrage,
+		SlippageBps:        aInfo.SlippageBps,
+		NetArbitrage:       aInfo.NetArbitrage,
+		NetProfit:          aInfo.NetProfit,
+		NetSpread:          aInfo.NetSpread,
+		BuyFees:            aInfo.BuyFees,
+		SellFees:           aInfo.SellFees,
+		MaxInvestAmount:    aInfo.MaxInvest,
+		QuoteAge:           qInfo.Age(),
+		BuyQuoteAge:        r.BuyInfo.Age(),
+		SellQuoteAge:       r.SellInfo.Age(),
+		IsExcitedArbitrage: aInfo.NetArbitrage.GreaterThanOrEqual(cfg.QuoteComparisonBot.ExcitedArbitrage),
+		IsExcitedSpread:    aInfo.NetSpread.GreaterThanOrEqual(cfg.QuoteComparisonBot.ExcitedSpreadOf(r.Pair)),
 	}
-	return nil
 }
 
 type arbitrageInfo struct {
 	Profit    decimal.Decimal
 	Spread    decimal.Decimal
 	Arbitrage decimal.Decimal
+
+	// the invest walked through both order books, Invest is less than asked
+	// when the books cannot fill all of it
+	Invest             decimal.Decimal
+	VWAPBuyPrice       decimal.Decimal
+	VWAPSellPrice      decimal.Decimal
+	EffectiveArbitrage decimal.Decimal
+	SlippageBps        decimal.Decimal
+
+	// NetSpread is the net profit per usdt
+	NetProfit         decimal.Decimal
+	NetArbitrage      decimal.Decimal
+	NetSpread         decimal.Decimal
+	BuyFees, SellFees dRepo.LegFees
+
+	// MaxInvest is the largest size clearing MinArbitrage net, nil when the books do not limit it
+	MaxInvest *decimal.Decimal
 }
 
-func calArbitrageInfo(invest, buyPrice, sellPrice decimal.Decimal) arbitrageInfo {
-	arbitrage := sellPrice.Sub(buyPrice).Div(buyPrice)
-	profit := arbitrage.Mul(invest)
-	spread := sellPrice.Sub(buyPrice)
-	return arbitrageInfo{
-		Profit:    profit,
-		Arbitrage: arbitrage,
-		Spread:    spread,
+// calArbitrageInfo buys invest on the asks and sells into the bids. Spread and Arbitrage
+// are top of book, Profit and the effective figures are gross for the walked size and
+// the net figures take the fees off them.
+func calArbitrageInfo(invest, minArbitrage decimal.Decimal, asks, bids orderBook, fees tradeFees) arbitrageInfo {
+	buyPrice, sellPrice := asks.top(), bids.top()
+	if !buyPrice.IsPositive() {
+		return arbitrageInfo{}
 	}
+
+	info := arbitrageInfo{
+		Arbitrage: sellPrice.Sub(buyPrice).Div(buyPrice),
+		Spread:    sellPrice.Sub(buyPrice),
+		MaxInvest: maxInvest(minArbitrage, asks, bids, fees),
+	}
+
+	if limit := capacity(asks, bids); limit != nil && limit.LessThan(invest) {
+		invest = *limit
+	}
+	f, ok := walk(invest, asks, bids, fees)
+	if !ok {
+		return info
+	}
+
+	info.Invest = f.Invest
+	info.Profit = f.Return.Sub(f.Invest)
+	info.VWAPBuyPrice = f.BuyPrice
+	info.VWAPSellPrice = f.SellPrice
+	info.EffectiveArbitrage = f.Arbitrage
+	// slippage of both legs against their top of book
+	info.SlippageBps = f.BuyPrice.Div(buyPrice).Sub(f.SellPrice.Div(sellPrice)).Mul(decimal.New(1, 4))
+
+	info.NetProfit = f.NetProfit
+	info.NetArbitrage = f.NetArbitrage
+	info.NetSpread = f.NetProfit.Div(f.Volume)
+	info.BuyFees, info.SellFees = f.BuyFees, f.SellFees
+	return info
 }
 
 func (u *telegramUseCase) notifyError(ctx context.Context, title string, errMsg string) {
@@ -191,6 +320,10 @@ type commandFactoryReq struct {
 	commandType constant.CommandType
 	tb          dRepo.TelegramBotRepo
 	quote       dRepo.QuoteRepo
+	depth       dRepo.DepthRepo
+	history     dRepo.HistoryRepo
+	settings    dRepo.SettingsRepo
+	args        []string
 }
 
 func newCommandFactory(req commandFactoryReq) commandHandler {
@@ -200,9 +333,21 @@ func newCommandFactory(req commandFactoryReq) commandHandler {
 	case constant.Help:
 		return newHelpCommand(req.cfg, req.tb)
 	case constant.Depth:
-		return newDepthCommand(req.tb)
+		return newDepthCommand(req.cfg, req.tb, req.depth, req.args)
 	case constant.Arbitrage:
-		return newArbitrageCommand(req.cfg, req.tb, req.quote)
+		return newArbitrageCommand(req.cfg, req.tb, req.quote, req.depth, req.args)
+	case constant.History:
+		return newHistoryCommand(req.cfg, req.tb, req.history, req.args)
+	case constant.Stats:
+		return newStatsCommand(req.cfg, req.tb, req.history, req.args)
+	case constant.Set:
+		return newSetCommand(req.cfg, req.tb, req.settings, req.args)
+	case constant.Settings:
+		return newSettingsCommand(req.cfg, req.tb, req.settings)
+	case constant.Subscribe:
+		return newSubscribeCommand(req.cfg, req.tb, req.settings, true)
+	case constant.Unsubscribe:
+		return newSubscribeCommand(req.cfg, req.tb, req.settings, false)
 	default:
 		return newUnknownCommand(req.tb)
 	}
@@ -254,17 +399,63 @@ func (c *helpCommand) Reply(toID int64, chatID int64) error {
 }
 
 type depthCommand struct {
-	tb dRepo.TelegramBotRepo
+	cfg   *config.TelegramCfg
+	tb    dRepo.TelegramBotRepo
+	depth dRepo.DepthRepo
+	args  []string
 }
 
-func newDepthCommand(tb dRepo.TelegramBotRepo) commandHandler {
-	return &depthCommand{tb: tb}
+func newDepthCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, depth dRepo.DepthRepo, args []string) commandHandler {
+	return &depthCommand{cfg: cfg, tb: tb, depth: depth, args: args}
 }
 
+var (
+	defaultDepthLevels = 5
+	maxDepthLevels     = 20
+)
+
+// Reply handles /depth [exchange] [pair] [levels], e.g. /depth MAX BTC 10
 func (c *depthCommand) Reply(toID int64, chatID int64) error {
-	msg := "我是懶惰老鼠，還沒串這個功能"
+	ctx := context.Background()
 
-	return c.tb.SendMessage(context.Background(), dRepo.SendMessageRequest{
+	exchange, pair, levels := constant.MAX, c.cfg.QuoteComparisonBot.DefaultPair(), defaultDepthLevels
+	for _, arg := range c.args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			levels = n
+			continue
+		}
+		if e, ok := constant.LookupExchange(arg); ok {
+			exchange = e
+			continue
+		}
+		p, ok := constant.LookupPair(arg)
+		if !ok {
+			return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，不認識 %s", arg))
+		}
+		pair = p
+	}
+
+	if levels < 1 || levels > maxDepthLevels {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，最多只看 %d 檔", maxDepthLevels))
+	}
+
+	depth, err := c.depth.GetDepth(ctx, dRepo.GetDepthRequest{Exchange: exchange, Base: pair.Base, Quote: pair.Quote, Limit: levels})
+	if errors.Is(err, dRepo.ErrDepthUnsupported) {
+		return c.reply(ctx, chatID, fmt.Sprintf("我是懶惰老鼠，%s 還沒串深度", exchange))
+	}
+	if err != nil {
+		log.Println("get depth failed: ", err.Error())
+		return err
+	}
+
+	return c.tb.SendDepthNotify(ctx, dRepo.SendDepthNotifyRequest{
+		ChatID: chatID,
+		Depth:  depth,
+	})
+}
+
+func (c *depthCommand) reply(ctx context.Context, chatID int64, msg string) error {
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
 		ChatID: chatID,
 		Text:   msg,
 	})
@@ -273,50 +464,106 @@ func (c *depthCommand) Reply(toID int64, chatID int64) error {
 type arbitrageCommand struct {
 	cfg   *config.TelegramCfg
 	quote dRepo.QuoteRepo
+	depth dRepo.DepthRepo
 	tb    dRepo.TelegramBotRepo
+	args  []string
 }
 
-func newArbitrageCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, quote dRepo.QuoteRepo) commandHandler {
-	return &arbitrageCommand{cfg: cfg, tb: tb, quote: quote}
+func newArbitrageCommand(cfg *config.TelegramCfg, tb dRepo.TelegramBotRepo, quote dRepo.QuoteRepo, depth dRepo.DepthRepo, args []string) commandHandler {
+	return &arbitrageCommand{cfg: cfg, tb: tb, quote: quote, depth: depth, args: args}
 }
 
+var maxArbitrageTopN = 10
+
+// Reply handles /arbitrage [pair] [n], e.g. /arbitrage USDC 5. It ranks every route
+// between the exchanges quoting the pair, lists the best n and details the best one.
 func (c *arbitrageCommand) Reply(toID int64, chatID int64) error {
 
 	// send message
 	ctx := context.Background()
+
+	pair, topN := c.cfg.QuoteComparisonBot.DefaultPair(), c.cfg.QuoteComparisonBot.ArbitrageTopN
+	for _, arg := range c.args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			topN = n
+			continue
+		}
+		p, ok := constant.LookupPair(arg)
+		if !ok {
+			return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+				ChatID: chatID,
+				Text:   fmt.Sprintf("我是懶惰老鼠，不認識 %s 這個幣", arg),
+			})
+		}
+		pair = p
+	}
+
+	if topN < 1 || topN > maxArbitrageTopN {
+		return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("我是懶惰老鼠，最多只排 %d 條路線", maxArbitrageTopN),
+		})
+	}
+
 	// get comparison quote
-	qInfo, err := c.quote.GetQuotations(ctx, dRepo.GetQuotationsRequest{})
+	qInfo, err := c.quote.GetQuotations(ctx, dRepo.NewGetQuotationsRequest(pair))
 	if err != nil {
 		log.Println("get quotations failed: ", err.Error())
 		return err
 	}
 
-	// calculate arbitrage info
-	aInfo := calArbitrageInfo(c.cfg.QuoteComparisonBot.DefaultInvest, qInfo.Infos[constant.Rybit].BuyPrice, qInfo.Infos[constant.MAX].SellPrice)
+	// never calculate on a frozen price
+	stale := make(map[dRepo.QuoteKey]bool, len(qInfo.Infos))
+	for key, info := range qInfo.Infos {
+		stale[key] = isStaleQuote(c.cfg, key.Exchange, info)
+	}
+
+	routes := scanRoutes(ctx, c.cfg, c.depth, pair, qInfo, stale, nil, topN)
+	problems := quoteProblems(c.cfg, pair, qInfo, stale)
+	if len(routes) == 0 {
+		msg := fmt.Sprintf("我是懶惰老鼠，%s 沒有兩家以上的報價可以比", pair)
+		if len(problems) > 0 {
+			msg = fmt.Sprintf("我是懶惰老鼠，%s 的報價有問題，先不算 (%s)", pair, joinErrors(problems))
+		}
+		return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+			ChatID: chatID,
+			Text:   msg,
+		})
+	}
 
-	var isExcitedArbitrage, isExcitedSpread bool
+	ranked := make([]dRepo.ArbitrageRoute, 0, len(routes))
+	for _, r := range routes {
+		ranked = append(ranked, dRepo.ArbitrageRoute{
+			ExchangeBuy:     r.Buy,
+			ExchangeSell:    r.Sell,
+			BuyPrice:        r.BuyInfo.BuyPrice,
+			SellPrice:       r.SellInfo.SellPrice,
+			InvestAmount:    r.Arbitrage.Invest,
+			NetArbitrage:    r.Arbitrage.NetArbitrage,
+			NetProfit:       r.Arbitrage.NetProfit,
+			MaxInvestAmount: r.Arbitrage.MaxInvest,
+		})
+	}
 
-	if aInfo.Arbitrage.GreaterThanOrEqual(c.cfg.QuoteComparisonBot.ExcitedArbitrage) {
-		isExcitedArbitrage = true
+	if err := c.tb.SendArbitrageRankNotify(ctx, dRepo.SendArbitrageRankNotifyRequest{
+		ChatID: chatID,
+		Pair:   pair,
+		Routes: ranked,
+	}); err != nil {
+		return err
 	}
 
-	if aInfo.Spread.GreaterThanOrEqual(c.cfg.QuoteComparisonBot.ExcitedSpread) {
-		isExcitedSpread = true
+	// send arbitrage notify of the best route
+	if err := c.tb.SendArbitrageNotify(ctx, arbitrageNotifyRequest(c.cfg, chatID, qInfo, routes[0])); err != nil {
+		return err
 	}
 
-	// send arbitrage notify
-	return c.tb.SendArbitrageNotify(ctx, dRepo.SendArbitrageNotifyRequest{
-		ChatID:             chatID,
-		InvestAmount:       c.cfg.QuoteComparisonBot.DefaultInvest,
-		ExchangeBuy:        constant.Rybit,
-		ExchangeSell:       constant.MAX,
-		BuyPrice:           qInfo.Infos[constant.Rybit].BuyPrice,
-		SellPrice:          qInfo.Infos[constant.MAX].SellPrice,
-		Spread:             aInfo.Spread,
-		Arbitrage:          aInfo.Arbitrage,
-		Profit:             aInfo.Profit,
-		IsExcitedArbitrage: isExcitedArbitrage,
-		IsExcitedSpread:    isExcitedSpread,
+	if len(problems) == 0 {
+		return nil
+	}
+	return c.tb.SendMessage(ctx, dRepo.SendMessageRequest{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("我是懶惰老鼠，這些報價有問題，沒有算進去 (%s)", joinErrors(problems)),
 	})
 }
 